@@ -0,0 +1,377 @@
+package spritz
+
+import (
+	"crypto/cipher"
+	"sync"
+)
+
+const (
+	// NonceSize is the size, in bytes, of the nonces used by the Spritz AEAD.
+	NonceSize = 16
+
+	// TagSize is the size, in bytes, of the authentication tags produced by the
+	// Spritz AEAD. Sealed messages are this many bytes longer than their
+	// plaintexts.
+	TagSize = 32
+
+	// CommitmentSize is the size, in bytes, of the key commitments appended by
+	// AEADs returned from NewAEADCommitting.
+	CommitmentSize = 32
+)
+
+var _ cipher.AEAD = &AEAD{}
+
+// NewAEAD returns a new instance of the Spritz AEAD using the given key.
+//
+// Two independent 32-byte subkeys are derived from the key with Expand: an
+// encryption key with the info "spritz aead encryption" and an authentication
+// key with the info "spritz aead authentication". Each message is encrypted
+// with a keystream derived by absorbing a stop and the nonce into the state
+// keyed with the encryption key, and authenticated with a tag squeezed from the
+// state keyed with the authentication key after absorbing a stop, the nonce,
+// the additional data, and the ciphertext. Nonces must never be reused with the
+// same key.
+func NewAEAD(key []byte) (*AEAD, error) {
+	if len(key) == 0 {
+		return nil, ErrEmptyKey
+	}
+	return &AEAD{newAEADKeys(key)}, nil
+}
+
+// AEAD is an instance of the Spritz AEAD. It implements cipher.AEAD, and also
+// supports sealing messages with their tags stored separately.
+type AEAD struct {
+	aeadKeys
+}
+
+// NewAEADStrict returns a new instance of the Spritz AEAD using the given key,
+// like NewAEAD, which also remembers every nonce passed to Seal and panics if
+// one is reused. It is meant for development and for high-assurance
+// deployments which want to catch nonce reuse before it leaks plaintext.
+// Because every nonce is kept for the lifetime of the AEAD, its memory use grows
+// by at least NonceSize bytes per sealed message, so it should be scoped to a
+// single connection or session rather than shared for a key's lifetime.
+func NewAEADStrict(key []byte) (*StrictAEAD, error) {
+	if len(key) == 0 {
+		return nil, ErrEmptyKey
+	}
+	return &StrictAEAD{
+		aead:   AEAD{newAEADKeys(key)},
+		nonces: make(map[string]struct{}),
+	}, nil
+}
+
+// NewAEADCommitting returns a new instance of the Spritz AEAD using the given
+// key, like NewAEAD, which also commits each sealed message to its key. Like
+// most stream-cipher AEADs, those returned by NewAEAD are not key-committing: a
+// ciphertext can be crafted which opens under more than one key, which breaks
+// protocols that try several keys or that let an attacker choose among them.
+//
+// A committing AEAD appends a CommitmentSize-byte commitment after the tag,
+// which is squeezed from a state keyed with a third subkey, derived with the
+// info "spritz aead commitment", after absorbing a stop and the nonce. It
+// depends on the key and nonce but not on the message, and Open checks it in
+// constant time before the tag, returning ErrWrongKey if the message was
+// sealed with a different key. Sealed messages are TagSize+CommitmentSize
+// bytes longer than their plaintexts, and each message costs one more squeeze.
+func NewAEADCommitting(key []byte) (*CommittingAEAD, error) {
+	if len(key) == 0 {
+		return nil, ErrEmptyKey
+	}
+	return &CommittingAEAD{
+		aead:   AEAD{newAEADKeys(key)},
+		commit: subkeyState(key, "spritz aead commitment"),
+	}, nil
+}
+
+var _ cipher.AEAD = &CommittingAEAD{}
+
+// CommittingAEAD is an instance of the Spritz AEAD which commits each sealed
+// message to its key. It implements cipher.AEAD, and also supports sealing
+// messages with their tags stored separately, in which case the commitment is
+// appended to the tag.
+type CommittingAEAD struct {
+	aead   AEAD
+	commit *state
+}
+
+// NonceSize returns the size of the nonce that must be passed to Seal and Open.
+func (a *CommittingAEAD) NonceSize() int {
+	return NonceSize
+}
+
+// Overhead returns the number of bytes a sealed message is longer than its
+// plaintext, which is the size of the authentication tag and the commitment.
+func (a *CommittingAEAD) Overhead() int {
+	return TagSize + CommitmentSize
+}
+
+// Seal encrypts and authenticates plaintext, authenticates the additional
+// data, and appends the ciphertext followed by its tag and commitment to dst.
+// It panics if nonce is not NonceSize bytes long.
+func (a *CommittingAEAD) Seal(dst, nonce, plaintext, data []byte) []byte {
+	ret := a.aead.Seal(dst, nonce, plaintext, data)
+	ret, out := sliceForAppend(ret, CommitmentSize)
+	a.commitment(nonce, out)
+	return ret
+}
+
+// Open checks the commitment of a sealed message, authenticates and decrypts
+// it, verifies the additional data, and appends the plaintext to dst. It
+// returns ErrWrongKey if the message was committed to a different key, or
+// ErrAuthFailed if authentication fails, and in either case writes no
+// plaintext. It panics if nonce is not NonceSize bytes long.
+func (a *CommittingAEAD) Open(dst, nonce, ciphertext, data []byte) ([]byte, error) {
+	if len(nonce) != NonceSize {
+		panic("spritz: incorrect nonce length given to AEAD")
+	}
+
+	if len(ciphertext) < TagSize+CommitmentSize {
+		return nil, ErrAuthFailed
+	}
+
+	split := len(ciphertext) - TagSize - CommitmentSize
+	return a.OpenDetached(dst, nonce, ciphertext[:split], ciphertext[split:], data)
+}
+
+// SealDetached encrypts and authenticates plaintext and authenticates the
+// additional data, like Seal, but returns the tag followed by the commitment
+// separately instead of appending them to the ciphertext. The ciphertext is
+// appended to dst.
+func (a *CommittingAEAD) SealDetached(dst, nonce, plaintext, data []byte) (ciphertext, tag []byte) {
+	ciphertext, tag = a.aead.SealDetached(dst, nonce, plaintext, data)
+	tag, out := sliceForAppend(tag, CommitmentSize)
+	a.commitment(nonce, out)
+	return ciphertext, tag
+}
+
+// OpenDetached checks the commitment of a ciphertext sealed by SealDetached,
+// authenticates and decrypts it, and appends the plaintext to dst, like Open.
+func (a *CommittingAEAD) OpenDetached(dst, nonce, ciphertext, tag, data []byte) ([]byte, error) {
+	if len(nonce) != NonceSize {
+		panic("spritz: incorrect nonce length given to AEAD")
+	}
+
+	if len(tag) != TagSize+CommitmentSize {
+		return nil, ErrAuthFailed
+	}
+
+	var expected [CommitmentSize]byte
+	a.commitment(nonce, expected[:])
+	if !Equal(expected[:], tag[TagSize:]) {
+		return nil, ErrWrongKey
+	}
+	return a.aead.OpenDetached(dst, nonce, ciphertext, tag[:TagSize], data)
+}
+
+// commitment squeezes the key commitment for the given nonce into out.
+func (a *CommittingAEAD) commitment(nonce, out []byte) {
+	s := a.commit.clone()
+	s.absorbStop()
+	s.absorb(nonce)
+	s.squeeze(out)
+}
+
+var _ cipher.AEAD = &StrictAEAD{}
+
+// StrictAEAD is an instance of the Spritz AEAD which panics if a nonce is
+// reused. It implements cipher.AEAD, and also supports sealing messages with
+// their tags stored separately. It is safe for concurrent use.
+type StrictAEAD struct {
+	aead   AEAD
+	mu     sync.Mutex
+	nonces map[string]struct{}
+}
+
+// NonceSize returns the size of the nonce that must be passed to Seal and Open.
+func (a *StrictAEAD) NonceSize() int {
+	return NonceSize
+}
+
+// Overhead returns the number of bytes a sealed message is longer than its
+// plaintext, which is the size of the authentication tag.
+func (a *StrictAEAD) Overhead() int {
+	return TagSize
+}
+
+// Seal encrypts and authenticates plaintext, like AEAD.Seal, and panics if
+// nonce has already been passed to Seal or SealDetached.
+func (a *StrictAEAD) Seal(dst, nonce, plaintext, data []byte) []byte {
+	a.use(nonce)
+	return a.aead.Seal(dst, nonce, plaintext, data)
+}
+
+// Open authenticates and decrypts a sealed message, like AEAD.Open.
+func (a *StrictAEAD) Open(dst, nonce, ciphertext, data []byte) ([]byte, error) {
+	return a.aead.Open(dst, nonce, ciphertext, data)
+}
+
+// SealDetached encrypts and authenticates plaintext, like AEAD.SealDetached,
+// and panics if nonce has already been passed to Seal or SealDetached.
+func (a *StrictAEAD) SealDetached(dst, nonce, plaintext, data []byte) (ciphertext, tag []byte) {
+	a.use(nonce)
+	return a.aead.SealDetached(dst, nonce, plaintext, data)
+}
+
+// OpenDetached authenticates and decrypts a message sealed by SealDetached,
+// like AEAD.OpenDetached.
+func (a *StrictAEAD) OpenDetached(dst, nonce, ciphertext, tag, data []byte) ([]byte, error) {
+	return a.aead.OpenDetached(dst, nonce, ciphertext, tag, data)
+}
+
+// use records nonce as used, and panics if it already was.
+func (a *StrictAEAD) use(nonce []byte) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.nonces[string(nonce)]; ok {
+		panic("spritz: nonce reused")
+	}
+	a.nonces[string(nonce)] = struct{}{}
+}
+
+// aeadKeys holds the states keyed with the encryption and authentication
+// subkeys of an AEAD key.
+type aeadKeys struct {
+	enc, mac *state
+}
+
+func newAEADKeys(key []byte) aeadKeys {
+	return aeadKeys{
+		enc: subkeyState(key, "spritz aead encryption"),
+		mac: subkeyState(key, "spritz aead authentication"),
+	}
+}
+
+// subkeyState returns a state keyed with the 32-byte subkey of key for the
+// given label.
+func subkeyState(key []byte, label string) *state {
+	subkey := make([]byte, 32)
+	expand(key, []byte(label), subkey)
+
+	var s state
+	s.initialize(256)
+	s.keySetup(subkey)
+	return &s
+}
+
+// setup returns the keystream and MAC states for the given nonce.
+func (k aeadKeys) setup(nonce []byte) (ks, mac *state) {
+	ks = k.enc.clone()
+	ks.absorbStop()
+	ks.absorb(nonce)
+
+	mac = k.mac.clone()
+	mac.absorbStop()
+	mac.absorb(nonce)
+	return ks, mac
+}
+
+// NonceSize returns the size of the nonce that must be passed to Seal and Open.
+func (AEAD) NonceSize() int {
+	return NonceSize
+}
+
+// Overhead returns the number of bytes a sealed message is longer than its
+// plaintext, which is the size of the authentication tag.
+func (AEAD) Overhead() int {
+	return TagSize
+}
+
+// Seal encrypts and authenticates plaintext, authenticates the additional
+// data, and appends the ciphertext followed by its TagSize-byte tag to dst. It
+// panics if nonce is not NonceSize bytes long.
+func (a AEAD) Seal(dst, nonce, plaintext, data []byte) []byte {
+	ret, out := sliceForAppend(dst, len(plaintext)+TagSize)
+	a.seal(nonce, plaintext, data, out[:len(plaintext)], out[len(plaintext):])
+	return ret
+}
+
+// Open authenticates and decrypts a ciphertext and its appended tag, verifies
+// the additional data, and appends the plaintext to dst. If authentication
+// fails, it returns ErrAuthFailed and writes no plaintext. It panics if nonce
+// is not NonceSize bytes long.
+func (a AEAD) Open(dst, nonce, ciphertext, data []byte) ([]byte, error) {
+	if len(nonce) != NonceSize {
+		panic("spritz: incorrect nonce length given to AEAD")
+	}
+
+	if len(ciphertext) < TagSize {
+		return nil, ErrAuthFailed
+	}
+
+	split := len(ciphertext) - TagSize
+	return a.OpenDetached(dst, nonce, ciphertext[:split], ciphertext[split:], data)
+}
+
+// SealDetached encrypts and authenticates plaintext and authenticates the
+// additional data, like Seal, but returns the TagSize-byte tag separately
+// instead of appending it to the ciphertext. The ciphertext is appended to dst.
+func (a AEAD) SealDetached(dst, nonce, plaintext, data []byte) (ciphertext, tag []byte) {
+	ret, out := sliceForAppend(dst, len(plaintext))
+	tag = make([]byte, TagSize)
+	a.seal(nonce, plaintext, data, out, tag)
+	return ret, tag
+}
+
+// OpenDetached authenticates and decrypts a ciphertext sealed by SealDetached,
+// comparing tag in constant time, and appends the plaintext to dst. If
+// authentication fails, it returns ErrAuthFailed and writes no plaintext.
+func (a AEAD) OpenDetached(dst, nonce, ciphertext, tag, data []byte) ([]byte, error) {
+	if len(nonce) != NonceSize {
+		panic("spritz: incorrect nonce length given to AEAD")
+	}
+
+	ks, mac := a.setup(nonce)
+
+	var actual [TagSize]byte
+	computeTag(mac, data, ciphertext, actual[:])
+	if !Equal(actual[:], tag) {
+		return nil, ErrAuthFailed
+	}
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	for i, v := range ciphertext {
+		out[i] = v ^ byte(ks.drip())
+	}
+	return ret, nil
+}
+
+// seal encrypts plaintext into ciphertext and writes its tag to tag.
+func (a AEAD) seal(nonce, plaintext, data, ciphertext, tag []byte) {
+	if len(nonce) != NonceSize {
+		panic("spritz: incorrect nonce length given to AEAD")
+	}
+
+	ks, mac := a.setup(nonce)
+	for i, v := range plaintext {
+		ciphertext[i] = v ^ byte(ks.drip())
+	}
+	computeTag(mac, data, ciphertext, tag)
+}
+
+// computeTag absorbs the additional data and the ciphertext into the MAC state
+// and squeezes the authentication tag into out.
+func computeTag(mac *state, data, ciphertext, out []byte) {
+	mac.absorbStop()
+	mac.absorb(data)
+	mac.absorbStop()
+	mac.absorb(ciphertext)
+	mac.absorbStop()
+	mac.absorbByte(len(out))
+	mac.squeeze(out)
+}
+
+// sliceForAppend takes a slice and a requested number of bytes. It returns a
+// slice with the contents of the given slice followed by that many bytes and a
+// second slice that aliases into it and contains only the extra bytes.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
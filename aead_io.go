@@ -0,0 +1,182 @@
+package spritz
+
+import (
+	"errors"
+	"io"
+)
+
+var (
+	errChunkSize    = errors.New("spritz: chunk size must be positive")
+	errWriterClosed = errors.New("spritz: write to a closed writer")
+)
+
+// NewEncryptWriter returns an io.WriteCloser which seals everything written to
+// it with a StreamEncrypter using the given key and nonce, and writes the sealed
+// chunks to w. It must be closed to write the final chunk and tag; closing it
+// also closes w if w is an io.Closer. Its output can be opened with
+// NewDecryptReader using the same key, nonce, and chunk size.
+//
+// The plaintext is split into chunks of exactly chunkSize bytes, followed by a
+// final chunk of fewer than chunkSize bytes, which is empty if the plaintext is
+// a multiple of chunkSize long. Each chunk is written as the output of
+// StreamEncrypter.Seal, its ciphertext followed by its TagSize-byte tag, and the
+// final chunk is followed by the TagSize-byte tag returned by
+// StreamEncrypter.Close. Writing to it after it has been closed returns an
+// error, and closing it again returns the result of the first Close.
+func NewEncryptWriter(w io.Writer, key, nonce []byte, chunkSize int) (io.WriteCloser, error) {
+	if chunkSize <= 0 {
+		return nil, errChunkSize
+	}
+
+	e, err := NewStreamEncrypter(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, e: e, buf: make([]byte, 0, chunkSize)}, nil
+}
+
+type encryptWriter struct {
+	w   io.Writer
+	e   *StreamEncrypter
+	buf []byte // the current, incomplete chunk
+
+	closed   bool
+	closeErr error // the result of the first Close
+}
+
+func (w *encryptWriter) Write(p []byte) (n int, err error) {
+	if w.closed {
+		return 0, errWriterClosed
+	}
+	for len(p) > 0 {
+		m := copy(w.buf[len(w.buf):cap(w.buf)], p)
+		w.buf = w.buf[:len(w.buf)+m]
+		p = p[m:]
+		n += m
+
+		if len(w.buf) == cap(w.buf) {
+			if err := w.write(w.e.Seal(w.buf)); err != nil {
+				return n, err
+			}
+			w.buf = w.buf[:0]
+		}
+	}
+	return n, nil
+}
+
+func (w *encryptWriter) Close() error {
+	if w.closed {
+		return w.closeErr
+	}
+	w.closed = true
+	w.closeErr = w.close()
+	return w.closeErr
+}
+
+func (w *encryptWriter) close() error {
+	if err := w.write(w.e.Seal(w.buf)); err != nil {
+		return err
+	}
+	if err := w.write(w.e.Close()); err != nil {
+		return err
+	}
+
+	w.buf = w.buf[:cap(w.buf)]
+	for i := range w.buf {
+		w.buf[i] = 0
+	}
+	if c, ok := w.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (w *encryptWriter) write(p []byte) error {
+	n, err := w.w.Write(p)
+	if err != nil {
+		return err
+	}
+	if n != len(p) {
+		return io.ErrShortWrite
+	}
+	return nil
+}
+
+// NewDecryptReader returns an io.Reader which opens the sealed chunks written
+// by NewEncryptWriter with the same key, nonce, and chunk size, as they are read
+// from r. The plaintext of each chunk is returned only once its tag has been
+// verified, and the reader buffers no more than one sealed chunk and the
+// TagSize bytes after it. If a chunk fails to verify, or the message has been
+// truncated, the reader returns ErrAuthFailed and no further plaintext.
+// Plaintext returned before such a failure has been authenticated as a prefix
+// of the message, but callers must read until io.EOF to know that the message
+// is complete.
+func NewDecryptReader(r io.Reader, key, nonce []byte, chunkSize int) (io.Reader, error) {
+	if chunkSize <= 0 {
+		return nil, errChunkSize
+	}
+
+	d, err := NewStreamDecrypter(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{
+		r:     r,
+		d:     d,
+		frame: chunkSize + TagSize,
+		buf:   make([]byte, 0, chunkSize+2*TagSize),
+	}, nil
+}
+
+type decryptReader struct {
+	r     io.Reader
+	d     *StreamDecrypter
+	frame int    // the size of a sealed chunk of chunkSize bytes
+	buf   []byte // sealed data read from r but not yet opened
+	out   []byte // opened plaintext not yet returned
+	err   error  // returned once out is empty
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.next()
+	}
+
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// next reads and opens the next sealed chunk.
+func (r *decryptReader) next() {
+	n, err := io.ReadFull(r.r, r.buf[len(r.buf):cap(r.buf)])
+	r.buf = r.buf[:len(r.buf)+n]
+
+	switch err {
+	case nil:
+		// a full buffer holds more than a final chunk and tag can, so it
+		// starts with a full chunk
+		r.out, r.err = r.d.Open(r.buf[:r.frame])
+		r.buf = r.buf[:copy(r.buf, r.buf[r.frame:])]
+	case io.EOF, io.ErrUnexpectedEOF:
+		// the buffer holds the final chunk followed by the final tag
+		if len(r.buf) < 2*TagSize {
+			r.err = ErrAuthFailed
+			return
+		}
+		last := len(r.buf) - TagSize
+		if r.out, r.err = r.d.Open(r.buf[:last]); r.err == nil {
+			r.err = r.d.Close(r.buf[last:])
+		}
+		if r.err != nil {
+			r.out = nil
+		} else {
+			r.err = io.EOF
+		}
+	default:
+		r.err = err
+	}
+}
@@ -0,0 +1,132 @@
+package spritz_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/codahale/spritz"
+)
+
+const testChunkSize = 64
+
+func encryptChunks(t *testing.T, key, nonce, msg []byte) []byte {
+	var buf bytes.Buffer
+	w, err := spritz.NewEncryptWriter(&buf, key, nonce, testChunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < len(msg); i += 10 {
+		end := i + 10
+		if end > len(msg) {
+			end = len(msg)
+		}
+		if _, err := w.Write(msg[i:end]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestEncryptWriterRoundTrip(t *testing.T) {
+	key, nonce := []byte("arcfour"), make([]byte, spritz.NonceSize)
+	msg := bytes.Repeat([]byte("hello world "), 100)
+
+	for _, n := range []int{0, 1, testChunkSize - 1, testChunkSize, testChunkSize + 1, 3 * testChunkSize, len(msg)} {
+		sealed := encryptChunks(t, key, nonce, msg[:n])
+		if expected := (n/testChunkSize+2)*spritz.TagSize + n; len(sealed) != expected {
+			t.Errorf("Sealed %d bytes into %d bytes but expected %d", n, len(sealed), expected)
+		}
+
+		r, err := spritz.NewDecryptReader(bytes.NewReader(sealed), key, nonce, testChunkSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("Couldn't open %d bytes: %v", n, err)
+		}
+
+		if !bytes.Equal(out, msg[:n]) {
+			t.Errorf("Opened %d bytes as \n%x\n but expected\n%x", n, out, msg[:n])
+		}
+	}
+}
+
+func TestDecryptReaderTampering(t *testing.T) {
+	key, nonce := []byte("arcfour"), make([]byte, spritz.NonceSize)
+	msg := bytes.Repeat([]byte("hello world "), 100)
+	sealed := encryptChunks(t, key, nonce, msg)
+
+	frame := testChunkSize + spritz.TagSize
+	modified := append([]byte(nil), sealed...)
+	modified[frame+1] ^= 1
+
+	r, err := spritz.NewDecryptReader(bytes.NewReader(modified), key, nonce, testChunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := io.ReadAll(r)
+	if err != spritz.ErrAuthFailed {
+		t.Errorf("Reading a modified middle chunk returned %v", err)
+	}
+
+	if !bytes.Equal(out, msg[:testChunkSize]) {
+		t.Errorf("Read \n%x\n before the modified chunk but expected\n%x", out, msg[:testChunkSize])
+	}
+
+	for _, n := range []int{len(sealed) - spritz.TagSize, frame, spritz.TagSize} {
+		r, err := spritz.NewDecryptReader(bytes.NewReader(sealed[:n]), key, nonce, testChunkSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := io.ReadAll(r); err != spritz.ErrAuthFailed {
+			t.Errorf("Reading a message truncated to %d bytes returned %v", n, err)
+		}
+	}
+}
+
+func TestEncryptWriterChunkSize(t *testing.T) {
+	key, nonce := []byte("arcfour"), make([]byte, spritz.NonceSize)
+
+	if _, err := spritz.NewEncryptWriter(io.Discard, key, nonce, 0); err == nil {
+		t.Error("Created an encrypter with a chunk size of zero")
+	}
+
+	if _, err := spritz.NewDecryptReader(bytes.NewReader(nil), key, nonce, 0); err == nil {
+		t.Error("Created a decrypter with a chunk size of zero")
+	}
+}
+
+func TestEncryptWriterClosed(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := spritz.NewEncryptWriter(&buf, []byte("arcfour"), make([]byte, spritz.NonceSize), testChunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Write([]byte("hello world")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	sealed := append([]byte(nil), buf.Bytes()...)
+
+	if err := w.Close(); err != nil {
+		t.Errorf("Second Close returned %v", err)
+	}
+	if n, err := w.Write([]byte("more")); n != 0 || err == nil {
+		t.Errorf("Write after Close returned %d, %v", n, err)
+	}
+	if !bytes.Equal(buf.Bytes(), sealed) {
+		t.Error("Wrote more output after Close")
+	}
+}
@@ -0,0 +1,170 @@
+package spritz
+
+import "errors"
+
+var errNonceLength = errors.New("spritz: incorrect nonce length")
+
+// NewStreamEncrypter returns an encrypter for a message which is too large to
+// seal at once, and is instead sealed as a sequence of chunks. Each chunk is
+// encrypted and authenticated with its own tag, and Close produces a final tag
+// which authenticates the sequence as a whole, including where it ends. The
+// nonce must be NonceSize bytes long, and must never be reused with the same
+// key.
+//
+// The keystream and authentication states are derived exactly as by NewAEAD.
+// The authentication state then absorbs, for each chunk in order, a stop, the
+// chunk's additional data (empty for chunks sealed with Seal), a stop, and the
+// chunk's ciphertext. A chunk's tag is squeezed from a copy of the
+// authentication state after absorbing a stop, a zero, a stop, and the tag size;
+// the final tag is squeezed the same way but with a one instead of a zero.
+func NewStreamEncrypter(key, nonce []byte) (*StreamEncrypter, error) {
+	ks, mac, err := newChunkedStates(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return &StreamEncrypter{ks: ks, mac: mac}, nil
+}
+
+// StreamEncrypter seals a message as a sequence of chunks.
+type StreamEncrypter struct {
+	ks, mac *state
+	closed  bool
+}
+
+// Seal encrypts chunk and returns its ciphertext followed by its TagSize-byte
+// tag. Chunks may be of any size, including empty. It is equivalent to
+// SealWithData with no additional data.
+func (e *StreamEncrypter) Seal(chunk []byte) []byte {
+	return e.SealWithData(chunk, nil)
+}
+
+// SealWithData encrypts chunk and returns its ciphertext followed by its
+// TagSize-byte tag, which also authenticates the chunk's additional data. The
+// additional data is not encrypted or included in the output, and must be
+// passed to StreamDecrypter.OpenWithData to open the chunk.
+func (e *StreamEncrypter) SealWithData(chunk, data []byte) []byte {
+	if e.closed {
+		panic("spritz: Seal called after Close")
+	}
+
+	out := make([]byte, len(chunk)+TagSize)
+	ciphertext := out[:len(chunk)]
+	for i, v := range chunk {
+		ciphertext[i] = v ^ byte(e.ks.drip())
+	}
+
+	absorbChunk(e.mac, data, ciphertext)
+	chunkTag(e.mac, 0, out[len(chunk):])
+	return out
+}
+
+// Close returns the final TagSize-byte tag for the sequence of chunks. No more
+// chunks may be sealed after Close.
+func (e *StreamEncrypter) Close() []byte {
+	e.closed = true
+	out := make([]byte, TagSize)
+	chunkTag(e.mac, 1, out)
+	return out
+}
+
+// NewStreamDecrypter returns a decrypter for a message sealed by a
+// StreamEncrypter with the same key and nonce.
+func NewStreamDecrypter(key, nonce []byte) (*StreamDecrypter, error) {
+	ks, mac, err := newChunkedStates(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return &StreamDecrypter{ks: ks, mac: mac}, nil
+}
+
+// StreamDecrypter opens a message sealed as a sequence of chunks.
+type StreamDecrypter struct {
+	ks, mac *state
+	failed  bool
+}
+
+// Open verifies and decrypts the next sealed chunk. If the chunk has been
+// modified, reordered, or sealed under a different key or nonce, Open returns an
+// error and no plaintext, and every later call to Open or Close fails as well.
+// Once every chunk has been opened, Close must be called to verify that the
+// message was not truncated. It is equivalent to OpenWithData with no additional
+// data.
+func (d *StreamDecrypter) Open(sealed []byte) ([]byte, error) {
+	return d.OpenWithData(sealed, nil)
+}
+
+// OpenWithData verifies and decrypts the next sealed chunk, like Open, along
+// with the additional data it was sealed with. If the additional data does not
+// match, it returns ErrAuthFailed, and every later call fails as well.
+func (d *StreamDecrypter) OpenWithData(sealed, data []byte) ([]byte, error) {
+	if d.failed || len(sealed) < TagSize {
+		d.failed = true
+		return nil, ErrAuthFailed
+	}
+
+	ciphertext := sealed[:len(sealed)-TagSize]
+	absorbChunk(d.mac, data, ciphertext)
+
+	var actual [TagSize]byte
+	chunkTag(d.mac, 0, actual[:])
+	if !Equal(actual[:], sealed[len(ciphertext):]) {
+		d.failed = true
+		return nil, ErrAuthFailed
+	}
+
+	out := make([]byte, len(ciphertext))
+	for i, v := range ciphertext {
+		out[i] = v ^ byte(d.ks.drip())
+	}
+	return out, nil
+}
+
+// Close verifies the final tag returned by StreamEncrypter.Close, and returns
+// an error if it does not match the chunks opened so far.
+func (d *StreamDecrypter) Close(tag []byte) error {
+	if d.failed {
+		return ErrAuthFailed
+	}
+
+	var actual [TagSize]byte
+	chunkTag(d.mac, 1, actual[:])
+	if !Equal(actual[:], tag) {
+		d.failed = true
+		return ErrAuthFailed
+	}
+	return nil
+}
+
+// newChunkedStates returns the keystream and authentication states for a
+// chunked message.
+func newChunkedStates(key, nonce []byte) (ks, mac *state, err error) {
+	if len(key) == 0 {
+		return nil, nil, ErrEmptyKey
+	}
+	if len(nonce) != NonceSize {
+		return nil, nil, errNonceLength
+	}
+
+	ks, mac = newAEADKeys(key).setup(nonce)
+	return ks, mac, nil
+}
+
+// absorbChunk absorbs a chunk's additional data and ciphertext into the
+// authentication state.
+func absorbChunk(mac *state, data, ciphertext []byte) {
+	mac.absorbStop()
+	mac.absorb(data)
+	mac.absorbStop()
+	mac.absorb(ciphertext)
+}
+
+// chunkTag squeezes a tag from a copy of the authentication state, using final
+// to distinguish the tags of individual chunks from the final tag.
+func chunkTag(mac *state, final int, out []byte) {
+	s := mac.clone()
+	s.absorbStop()
+	s.absorbByte(final)
+	s.absorbStop()
+	s.absorbByte(len(out))
+	s.squeeze(out)
+}
@@ -0,0 +1,175 @@
+package spritz_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/codahale/spritz"
+)
+
+func sealChunks(t *testing.T, key, nonce []byte, chunks [][]byte) ([][]byte, []byte) {
+	e, err := spritz.NewStreamEncrypter(key, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sealed [][]byte
+	for _, c := range chunks {
+		sealed = append(sealed, e.Seal(c))
+	}
+	return sealed, e.Close()
+}
+
+func TestStreamAEADRoundTrip(t *testing.T) {
+	key, nonce := []byte("arcfour"), make([]byte, spritz.NonceSize)
+
+	many := make([][]byte, 10000)
+	for i := range many {
+		many[i] = []byte{byte(i)}
+	}
+
+	fixtures := []struct {
+		name   string
+		chunks [][]byte
+	}{
+		{"zero chunks", nil},
+		{"one empty chunk", [][]byte{{}}},
+		{"several chunks", [][]byte{[]byte("hello"), {}, []byte(" world")}},
+		{"many small chunks", many},
+	}
+
+	for _, f := range fixtures {
+		sealed, tag := sealChunks(t, key, nonce, f.chunks)
+
+		d, err := spritz.NewStreamDecrypter(key, nonce)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, c := range sealed {
+			out, err := d.Open(c)
+			if err != nil {
+				t.Fatalf("%s: couldn't open chunk %d: %v", f.name, i, err)
+			}
+
+			if !bytes.Equal(out, f.chunks[i]) {
+				t.Errorf("%s: chunk %d was %x but expected %x", f.name, i, out, f.chunks[i])
+			}
+		}
+
+		if err := d.Close(tag); err != nil {
+			t.Errorf("%s: couldn't verify final tag: %v", f.name, err)
+		}
+	}
+}
+
+func TestStreamAEADTampering(t *testing.T) {
+	key, nonce := []byte("arcfour"), make([]byte, spritz.NonceSize)
+	chunks := [][]byte{[]byte("one"), []byte("two"), []byte("three")}
+	sealed, tag := sealChunks(t, key, nonce, chunks)
+
+	open := func(sealed [][]byte, tag []byte) error {
+		d, err := spritz.NewStreamDecrypter(key, nonce)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, c := range sealed {
+			if out, err := d.Open(c); err != nil {
+				if out != nil {
+					t.Errorf("Open returned plaintext with an error: %x", out)
+				}
+				return err
+			}
+		}
+		return d.Close(tag)
+	}
+
+	modified := append([]byte(nil), sealed[1]...)
+	modified[0] ^= 1
+
+	fixtures := []struct {
+		name   string
+		sealed [][]byte
+		tag    []byte
+	}{
+		{"modified chunk", [][]byte{sealed[0], modified, sealed[2]}, tag},
+		{"reordered chunks", [][]byte{sealed[1], sealed[0], sealed[2]}, tag},
+		{"truncated message", sealed[:2], tag},
+		{"chunk tag as final tag", sealed[:2], sealed[2][len(sealed[2])-spritz.TagSize:]},
+		{"short chunk", [][]byte{sealed[0][:spritz.TagSize-1]}, tag},
+	}
+
+	for _, f := range fixtures {
+		if err := open(f.sealed, f.tag); err != spritz.ErrAuthFailed {
+			t.Errorf("Opened a message with a %s: %v", f.name, err)
+		}
+	}
+}
+
+func TestStreamAEADData(t *testing.T) {
+	key, nonce := []byte("arcfour"), make([]byte, spritz.NonceSize)
+	chunks := []string{"one", "two", "three"}
+	data := []string{"header 1", "", "header 3"}
+
+	e, err := spritz.NewStreamEncrypter(key, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sealed [][]byte
+	for i, c := range chunks {
+		sealed = append(sealed, e.SealWithData([]byte(c), []byte(data[i])))
+	}
+	tag := e.Close()
+
+	open := func(data []string) error {
+		d, err := spritz.NewStreamDecrypter(key, nonce)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, c := range sealed {
+			out, err := d.OpenWithData(c, []byte(data[i]))
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(out, []byte(chunks[i])) {
+				t.Errorf("Chunk %d was %q but expected %q", i, out, chunks[i])
+			}
+		}
+		return d.Close(tag)
+	}
+
+	if err := open(data); err != nil {
+		t.Errorf("Couldn't open chunks with their data: %v", err)
+	}
+
+	fixtures := []struct {
+		name string
+		data []string
+	}{
+		{"mismatched data", []string{"header 1", "", "header 4"}},
+		{"missing data", []string{"", "", ""}},
+		{"moved data", []string{"header 1", "header 3", ""}},
+	}
+
+	for _, f := range fixtures {
+		if err := open(f.data); err != spritz.ErrAuthFailed {
+			t.Errorf("Opened chunks with %s: %v", f.name, err)
+		}
+	}
+}
+
+func TestStreamAEADErrors(t *testing.T) {
+	if _, err := spritz.NewStreamEncrypter(nil, make([]byte, spritz.NonceSize)); err == nil {
+		t.Error("Created an encrypter with an empty key")
+	}
+
+	if _, err := spritz.NewStreamEncrypter([]byte("arcfour"), make([]byte, 3)); err == nil {
+		t.Error("Created an encrypter with a short nonce")
+	}
+
+	if _, err := spritz.NewStreamDecrypter([]byte("arcfour"), make([]byte, 3)); err == nil {
+		t.Error("Created a decrypter with a short nonce")
+	}
+}
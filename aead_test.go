@@ -0,0 +1,274 @@
+package spritz_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/codahale/spritz"
+)
+
+func TestAEADRoundTrip(t *testing.T) {
+	fixtures := []struct {
+		plaintext, data string
+	}{
+		{"", ""},
+		{"", "header"},
+		{"hello world", ""},
+		{"hello world", "header"},
+	}
+
+	a, err := spritz.NewAEAD([]byte("arcfour"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, a.NonceSize())
+
+	for _, f := range fixtures {
+		sealed := a.Seal(nil, nonce, []byte(f.plaintext), []byte(f.data))
+		if len(sealed) != len(f.plaintext)+a.Overhead() {
+			t.Errorf("Sealed %q was %d bytes but expected %d", f.plaintext, len(sealed), len(f.plaintext)+a.Overhead())
+		}
+
+		opened, err := a.Open(nil, nonce, sealed, []byte(f.data))
+		if err != nil {
+			t.Errorf("Couldn't open %q: %v", f.plaintext, err)
+		}
+
+		if !bytes.Equal(opened, []byte(f.plaintext)) {
+			t.Errorf("Opened %q but expected %q", opened, f.plaintext)
+		}
+	}
+}
+
+func TestAEADTampering(t *testing.T) {
+	a, err := spritz.NewAEAD([]byte("arcfour"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, a.NonceSize())
+	sealed := a.Seal(nil, nonce, []byte("hello world"), []byte("header"))
+
+	for i := range sealed {
+		c := append([]byte(nil), sealed...)
+		c[i] ^= 1
+		if out, err := a.Open(nil, nonce, c, []byte("header")); err == nil {
+			t.Errorf("Opened a message modified at byte %d: %q", i, out)
+		}
+	}
+
+	if _, err := a.Open(nil, nonce, sealed, []byte("footer")); err != spritz.ErrAuthFailed {
+		t.Errorf("Opened a message with the wrong additional data: %v", err)
+	}
+
+	nonce[0] ^= 1
+	if _, err := a.Open(nil, nonce, sealed, []byte("header")); err == nil {
+		t.Error("Opened a message with the wrong nonce")
+	}
+
+	if _, err := a.Open(nil, nonce, sealed[:a.Overhead()-1], nil); err == nil {
+		t.Error("Opened a message shorter than a tag")
+	}
+}
+
+func TestAEADEmptyKey(t *testing.T) {
+	if _, err := spritz.NewAEAD(nil); err != spritz.ErrEmptyKey {
+		t.Errorf("Created an AEAD with an empty key: %v", err)
+	}
+}
+
+func TestAEADSubkeys(t *testing.T) {
+	key := []byte("arcfour")
+	nonce := make([]byte, spritz.NonceSize)
+	plaintext := []byte("hello world")
+
+	a, err := spritz.NewAEAD(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed := a.Seal(nil, nonce, plaintext, nil)
+
+	// derive the encryption subkey as documented
+	s := spritz.NewSponge(256)
+	s.Absorb(key)
+	s.AbsorbStop()
+	s.Absorb([]byte("spritz aead encryption"))
+	s.AbsorbStop()
+	s.Absorb([]byte{0, 0, 0, 0, 0, 0, 0, 32})
+	encKey := make([]byte, 32)
+	s.Squeeze(encKey)
+
+	expected := spritz.Encrypt(encKey, nonce, plaintext)
+	if ciphertext := sealed[:len(plaintext)]; !bytes.Equal(ciphertext, expected) {
+		t.Errorf("Ciphertext was \n%x\n but expected\n%x", ciphertext, expected)
+	}
+
+	if bytes.Equal(sealed[:len(plaintext)], spritz.Encrypt(key, nonce, plaintext)) {
+		t.Error("Ciphertext was encrypted with the master key")
+	}
+}
+
+func TestAEADStrict(t *testing.T) {
+	a, err := spritz.NewAEADStrict([]byte("arcfour"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce := make([]byte, a.NonceSize())
+	sealed := a.Seal(nil, nonce, []byte("hello world"), nil)
+
+	if _, err := a.Open(nil, nonce, sealed, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	nonce[0] = 1
+	_ = a.Seal(nil, nonce, []byte("hello world"), nil)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Sealed two messages with the same nonce")
+		}
+	}()
+	_ = a.Seal(nil, nonce, []byte("goodbye"), nil)
+}
+
+func TestAEADStrictDetached(t *testing.T) {
+	a, err := spritz.NewAEADStrict([]byte("arcfour"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce := make([]byte, a.NonceSize())
+	ciphertext, tag := a.SealDetached(nil, nonce, []byte("hello world"), nil)
+	if _, err := a.OpenDetached(nil, nonce, ciphertext, tag, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Sealed a detached message with a used nonce")
+		}
+	}()
+	_, _ = a.SealDetached(nil, nonce, []byte("goodbye"), nil)
+}
+
+func TestAEADDetached(t *testing.T) {
+	a, err := spritz.NewAEAD([]byte("arcfour"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, a.NonceSize())
+	plaintext, data := []byte("hello world"), []byte("header")
+
+	ciphertext, tag := a.SealDetached(nil, nonce, plaintext, data)
+	if sealed := a.Seal(nil, nonce, plaintext, data); !bytes.Equal(append(ciphertext, tag...), sealed) {
+		t.Errorf("Detached output was \n%x\n but expected\n%x", append(ciphertext, tag...), sealed)
+	}
+
+	opened, err := a.OpenDetached(nil, nonce, ciphertext, tag, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Errorf("Opened %q but expected %q", opened, plaintext)
+	}
+
+	modified := append([]byte(nil), tag...)
+	modified[0] ^= 1
+	otherNonce := append([]byte(nil), nonce...)
+	otherNonce[0] ^= 1
+
+	fixtures := []struct {
+		name             string
+		nonce, tag, data []byte
+	}{
+		{"wrong tag", nonce, modified, data},
+		{"truncated tag", nonce, tag[:spritz.TagSize-1], data},
+		{"wrong additional data", nonce, tag, []byte("footer")},
+		{"wrong nonce", otherNonce, tag, data},
+	}
+
+	for _, f := range fixtures {
+		dst := make([]byte, 0, len(ciphertext))
+		out, err := a.OpenDetached(dst, f.nonce, ciphertext, f.tag, f.data)
+		if err != spritz.ErrAuthFailed {
+			t.Errorf("Opened a message with the %s: %v", f.name, err)
+		}
+		if out != nil || dst[:cap(dst)][0] != 0 {
+			t.Errorf("Wrote plaintext for a message with the %s", f.name)
+		}
+	}
+}
+
+func TestAEADCommitting(t *testing.T) {
+	a, err := spritz.NewAEADCommitting([]byte("arcfour"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := spritz.NewAEADCommitting([]byte("arcfour2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce := make([]byte, a.NonceSize())
+	plaintext, data := []byte("hello world"), []byte("header")
+	sealed := a.Seal(nil, nonce, plaintext, data)
+
+	if len(sealed) != len(plaintext)+a.Overhead() || a.Overhead() != spritz.TagSize+spritz.CommitmentSize {
+		t.Errorf("Sealed %d bytes with an overhead of %d", len(sealed), a.Overhead())
+	}
+
+	opened, err := a.Open(nil, nonce, sealed, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Errorf("Opened %q but expected %q", opened, plaintext)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := b.Open(nil, nonce, sealed, data); err != spritz.ErrWrongKey {
+			t.Errorf("Opened a message with the wrong key: %v", err)
+		}
+	}
+
+	modified := append([]byte(nil), sealed...)
+	modified[0] ^= 1
+	if _, err := a.Open(nil, nonce, modified, data); err != spritz.ErrAuthFailed {
+		t.Errorf("Opened a modified message: %v", err)
+	}
+
+	if _, err := a.Open(nil, nonce, sealed[:spritz.TagSize], data); err != spritz.ErrAuthFailed {
+		t.Errorf("Opened a truncated message: %v", err)
+	}
+
+	// the detached methods must not bypass the commitment
+	ciphertext, tag := a.SealDetached(nil, nonce, plaintext, data)
+	if joined := append(ciphertext, tag...); !bytes.Equal(joined, sealed) {
+		t.Errorf("Detached output was \n%x\n but expected\n%x", joined, sealed)
+	}
+
+	if _, err := a.OpenDetached(nil, nonce, ciphertext, tag[:spritz.TagSize], data); err != spritz.ErrAuthFailed {
+		t.Errorf("Opened a detached message without its commitment: %v", err)
+	}
+
+	if _, err := b.OpenDetached(nil, nonce, ciphertext, tag, data); err != spritz.ErrWrongKey {
+		t.Errorf("Opened a detached message with the wrong key: %v", err)
+	}
+}
+
+func BenchmarkAEADSeal(b *testing.B) {
+	a, err := spritz.NewAEAD([]byte("arcfour"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	nonce := make([]byte, a.NonceSize())
+	in := make([]byte, 1024)
+	out := make([]byte, 0, len(in)+a.Overhead())
+	b.SetBytes(int64(len(in)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		a.Seal(out, nonce, in, nil)
+	}
+}
@@ -0,0 +1,66 @@
+package spritz
+
+import (
+	"hash"
+	"io"
+)
+
+// NewBufferedHash returns a hash.Hash which collects data written to it in an
+// internal buffer and absorbs it into h in batches, which reduces the overhead
+// of many small writes. Its digests are identical to those of h. Data written to
+// the returned hash is only absorbed into h when the buffer fills, or when Sum
+// is called, so h should not be used directly while it is wrapped.
+func NewBufferedHash(h *Digest) hash.Hash {
+	return &bufferedHash{h: h, buf: make([]byte, 0, 4096)}
+}
+
+var _ io.ByteWriter = &bufferedHash{}
+
+type bufferedHash struct {
+	h   *Digest
+	buf []byte
+}
+
+func (b *bufferedHash) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		if len(b.buf) == cap(b.buf) {
+			b.flush()
+		}
+		m := copy(b.buf[len(b.buf):cap(b.buf)], p)
+		b.buf = b.buf[:len(b.buf)+m]
+		p = p[m:]
+	}
+	return n, nil
+}
+
+func (b *bufferedHash) WriteByte(c byte) error {
+	if len(b.buf) == cap(b.buf) {
+		b.flush()
+	}
+	b.buf = append(b.buf, c)
+	return nil
+}
+
+func (b *bufferedHash) Sum(p []byte) []byte {
+	b.flush()
+	return b.h.Sum(p)
+}
+
+func (b *bufferedHash) Reset() {
+	b.buf = b.buf[:0]
+	b.h.Reset()
+}
+
+func (b *bufferedHash) Size() int {
+	return b.h.Size()
+}
+
+func (b *bufferedHash) BlockSize() int {
+	return b.h.BlockSize()
+}
+
+func (b *bufferedHash) flush() {
+	b.h.s.absorb(b.buf)
+	b.buf = b.buf[:0]
+}
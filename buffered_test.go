@@ -0,0 +1,61 @@
+package spritz_test
+
+import (
+	"bytes"
+	"hash"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/codahale/spritz"
+)
+
+func TestBufferedHash(t *testing.T) {
+	msg := []byte(strings.Repeat("arcfour", 2000))
+
+	h := spritz.NewBufferedHash(spritz.NewHash(32))
+	for i, c := range msg {
+		if i%2 == 0 {
+			_ = h.(io.ByteWriter).WriteByte(c)
+		} else {
+			_, _ = h.Write([]byte{c})
+		}
+	}
+	_, _ = h.Write(msg)
+
+	expected := spritz.NewHash(32)
+	_, _ = expected.Write(msg)
+	_, _ = expected.Write(msg)
+
+	if !bytes.Equal(h.Sum(nil), expected.Sum(nil)) {
+		t.Errorf("Output was \n%x\n but expected\n%x", h.Sum(nil), expected.Sum(nil))
+	}
+
+	h.Reset()
+	_, _ = h.Write(msg)
+	expected.Reset()
+	_, _ = expected.Write(msg)
+
+	if !bytes.Equal(h.Sum(nil), expected.Sum(nil)) {
+		t.Errorf("Output after Reset was \n%x\n but expected\n%x", h.Sum(nil), expected.Sum(nil))
+	}
+}
+
+func benchmarkSingleByteWrites(b *testing.B, h hash.Hash) {
+	in := []byte{0}
+	b.SetBytes(1)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = h.Write(in)
+	}
+}
+
+func BenchmarkHashSingleByteWrites(b *testing.B) {
+	benchmarkSingleByteWrites(b, spritz.NewHash(32))
+}
+
+func BenchmarkBufferedHashSingleByteWrites(b *testing.B) {
+	benchmarkSingleByteWrites(b, spritz.NewBufferedHash(spritz.NewHash(32)))
+}
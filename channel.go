@@ -0,0 +1,94 @@
+package spritz
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	errReplay    = errors.New("spritz: message replayed or out of order")
+	errReflected = errors.New("spritz: message was sent by this channel")
+)
+
+// NewChannel returns a channel for sending and receiving a sequence of messages
+// encrypted with the Spritz AEAD using the given key, which manages nonces so
+// that none is ever reused. Each channel picks a random 8-byte prefix when it
+// is created, and the nonce of each message it encrypts is that prefix followed
+// by a message counter, as an 8-byte big-endian integer, starting at zero. The
+// random prefix keeps the nonces of different channels with the same key
+// apart. It returns ErrEmptyKey if the key is empty, or an error if the
+// system's random source fails.
+func NewChannel(key []byte) (*Channel, error) {
+	a, err := NewAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Channel{aead: a, received: make(map[[8]byte]uint64)}
+	if _, err := rand.Read(c.prefix[:]); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Channel encrypts and decrypts a sequence of messages with automatically
+// advancing nonces. It is not safe for concurrent use.
+type Channel struct {
+	aead   *AEAD
+	prefix [8]byte
+	sent   uint64 // the counter of the next message to encrypt
+
+	// for each sender's prefix, one more than the counter of the last message
+	// decrypted from it
+	received map[[8]byte]uint64
+}
+
+// EncryptNext seals plaintext with the channel's next nonce, and returns the
+// nonce along with the sealed message. The nonce must be sent along with the
+// ciphertext. It panics if the channel has run out of counter values.
+func (c *Channel) EncryptNext(plaintext []byte) (nonce, ciphertext []byte) {
+	if c.sent == 1<<64-1 {
+		panic("spritz: channel nonces exhausted")
+	}
+
+	nonce = make([]byte, NonceSize)
+	copy(nonce, c.prefix[:])
+	binary.BigEndian.PutUint64(nonce[8:], c.sent)
+	c.sent++
+	return nonce, c.aead.Seal(nil, nonce, plaintext, nil)
+}
+
+// DecryptNext opens a message sealed by another channel's EncryptNext, and
+// returns its plaintext. Messages from each sender, as identified by the
+// random prefix of their nonces, must be decrypted in the order they were
+// encrypted, though some may be skipped: a message whose counter is not greater
+// than that of the last message decrypted from the same sender is rejected as
+// a replay. A sender which restarts with a new prefix starts a new sequence.
+// Both directions of a conversation share a key, so a message carrying this
+// channel's own prefix is rejected, which keeps an attacker from reflecting a
+// channel's messages back to it. If the message cannot be authenticated, it
+// returns ErrAuthFailed.
+func (c *Channel) DecryptNext(nonce, ciphertext []byte) ([]byte, error) {
+	if len(nonce) != NonceSize {
+		return nil, errNonceLength
+	}
+
+	var prefix [8]byte
+	copy(prefix[:], nonce)
+	if prefix == c.prefix {
+		return nil, errReflected
+	}
+
+	counter := binary.BigEndian.Uint64(nonce[8:])
+	if counter < c.received[prefix] {
+		return nil, errReplay
+	}
+
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.received[prefix] = counter + 1
+	return plaintext, nil
+}
@@ -0,0 +1,114 @@
+package spritz_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/codahale/spritz"
+)
+
+func TestChannel(t *testing.T) {
+	key := []byte("arcfour")
+
+	sender, err := spritz.NewChannel(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver, err := spritz.NewChannel(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := []byte("hello world")
+	n1, c1 := sender.EncryptNext(msg)
+	n2, c2 := sender.EncryptNext(msg)
+
+	if bytes.Equal(n1, n2) {
+		t.Errorf("Encrypted two messages with the same nonce: %x", n1)
+	}
+	if bytes.Equal(c1, c2) {
+		t.Errorf("Encrypted the same plaintext to the same ciphertext: %x", c1)
+	}
+
+	for _, m := range []struct{ nonce, ciphertext []byte }{{n1, c1}, {n2, c2}} {
+		out, err := receiver.DecryptNext(m.nonce, m.ciphertext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out, msg) {
+			t.Errorf("Decrypted %q but expected %q", out, msg)
+		}
+	}
+
+	if _, err := receiver.DecryptNext(n1, c1); err == nil {
+		t.Error("Decrypted a replayed message")
+	}
+
+	n3, c3 := sender.EncryptNext(msg)
+	c3[0] ^= 1
+	if _, err := receiver.DecryptNext(n3, c3); err != spritz.ErrAuthFailed {
+		t.Errorf("Decrypted a modified message: %v", err)
+	}
+
+	other, err := spritz.NewChannel(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := other.EncryptNext(msg); bytes.Equal(n, n1) {
+		t.Errorf("Two channels used the same nonce: %x", n)
+	}
+
+	if _, err := spritz.NewChannel(nil); err != spritz.ErrEmptyKey {
+		t.Errorf("Created a channel with an empty key: %v", err)
+	}
+}
+
+func TestChannelReflected(t *testing.T) {
+	c, err := spritz.NewChannel([]byte("arcfour"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce, ciphertext := c.EncryptNext([]byte("hello world"))
+	if out, err := c.DecryptNext(nonce, ciphertext); err == nil {
+		t.Errorf("Decrypted a reflected message: %q", out)
+	}
+}
+
+func TestChannelSenders(t *testing.T) {
+	key := []byte("arcfour")
+	receiver, err := spritz.NewChannel(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a sender which restarts gets a new prefix and starts counting from zero
+	first, err := spritz.NewChannel(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := spritz.NewChannel(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := []byte("hello world")
+	var replay struct{ nonce, ciphertext []byte }
+	for i := 0; i < 3; i++ {
+		replay.nonce, replay.ciphertext = first.EncryptNext(msg)
+		if _, err := receiver.DecryptNext(replay.nonce, replay.ciphertext); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		nonce, ciphertext := second.EncryptNext(msg)
+		if _, err := receiver.DecryptNext(nonce, ciphertext); err != nil {
+			t.Fatalf("Rejected message %d from a second sender: %v", i, err)
+		}
+	}
+
+	if _, err := receiver.DecryptNext(replay.nonce, replay.ciphertext); err == nil {
+		t.Error("Decrypted a message replayed from the first sender")
+	}
+}
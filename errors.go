@@ -0,0 +1,28 @@
+package spritz
+
+import "errors"
+
+var (
+	// ErrEmptyKey is returned by constructors which require a non-empty key.
+	ErrEmptyKey = errors.New("spritz: empty key")
+
+	// ErrInvalidN is returned when a state size is smaller than 16, either by a
+	// checked constructor or when unmarshaling an encoded state.
+	ErrInvalidN = errors.New("spritz: state size must be at least 16")
+
+	// ErrShortBuffer is returned when an encoded value ends before all of its
+	// fields have been read.
+	ErrShortBuffer = errors.New("spritz: buffer too short")
+
+	// ErrAuthFailed is returned when a sealed message or tag fails
+	// authentication.
+	ErrAuthFailed = errors.New("spritz: message authentication failed")
+
+	// ErrWrongKey is returned by key-committing AEADs when a sealed message was
+	// committed to a different key.
+	ErrWrongKey = errors.New("spritz: message was sealed with a different key")
+
+	// ErrWeakKey is returned by checked constructors for keys which WeakKey
+	// reports as weak.
+	ErrWeakKey = errors.New("spritz: weak key")
+)
@@ -1,53 +1,476 @@
 package spritz
 
-import "hash"
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+)
+
+var (
+	_ hash.Hash     = &Digest{}
+	_ io.ByteWriter = &Digest{}
+	_ io.ReaderFrom = &Digest{}
+)
 
 // NewHash returns a new instance of the Spritz hash with the given output size.
-func NewHash(size int) hash.Hash {
-	var s state
-	s.initialize(256)
-	return hasher{size: size, s: &s}
+func NewHash(size int) *Digest {
+	return NewHashN(size, 256)
+}
+
+// NewHashN returns a new instance of the Spritz hash with the given output size
+// and a state size of N, which must be at least 16. As with NewStreamN, larger
+// states trade speed and memory for a larger permutation.
+func NewHashN(size, n int) *Digest {
+	checkN(n)
+	h := &Digest{size: size, n: n}
+	h.Reset()
+	return h
+}
+
+// NewHashPersonalized returns a new instance of the Spritz hash with the given
+// output size and personalization string. The personalization string is
+// absorbed between two stops before any data is written, so hashes of the
+// same data with different personalizations are unrelated, and none of them
+// equals a MAC keyed with the personalization string. This allows different
+// protocols or uses of the hash to avoid colliding with each other. An empty
+// personalization string is equivalent to NewHash.
+func NewHashPersonalized(size int, personalization []byte) *Digest {
+	h := &Digest{size: size, n: 256, personal: append([]byte(nil), personalization...)}
+	h.Reset()
+	return h
 }
 
 // NewMAC returns a new instance of the Spritz MAC with the given key and output
 // size.
-func NewMAC(key []byte, size int) hash.Hash {
+func NewMAC(key []byte, size int) *Digest {
+	h := &Digest{size: size, n: 256, key: append([]byte{}, key...)}
+	h.Reset()
+	return h
+}
+
+// Sum256 returns the 32-byte Spritz hash of data.
+func Sum256(data []byte) [32]byte {
+	var out [32]byte
+	sum(data, out[:])
+	return out
+}
+
+// Sum512 returns the 64-byte Spritz hash of data.
+func Sum512(data []byte) [64]byte {
+	var out [64]byte
+	sum(data, out[:])
+	return out
+}
+
+// SumReader returns the size-byte Spritz hash of everything read from r until
+// EOF. Any other error returned by r is returned along with a nil digest.
+func SumReader(r io.Reader, size int) ([]byte, error) {
+	h := NewHash(size)
+	if _, err := h.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// SumFile returns the size-byte Spritz hash of the contents of the named file,
+// which are read in chunks rather than loaded into memory. The file is always
+// closed before SumFile returns. Errors opening or reading the file are
+// wrapped with the failing operation, and returned along with a nil digest.
+func SumFile(path string, size int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("spritz: opening file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	h := NewHash(size)
+	if _, err := h.ReadFrom(f); err != nil {
+		return nil, fmt.Errorf("spritz: reading file: %w", err)
+	}
+	return h.Sum(nil), nil
+}
+
+// SumHex returns the lowercase hexadecimal encoding of the size-byte Spritz hash
+// of data, as hex.EncodeToString would produce for the digest of NewHash.
+func SumHex(data []byte, size int) string {
+	out := make([]byte, size)
+	sum(data, out)
+	return hex.EncodeToString(out)
+}
+
+// SumMulti returns the size-byte Spritz hash of the concatenation of chunks,
+// without building the concatenation. Chunk boundaries do not affect the
+// digest, so the chunks "ab" and "c" hash the same as "a" and "bc"; use
+// SumMultiFramed when the chunks are separate fields.
+func SumMulti(size int, chunks ...[]byte) []byte {
+	h := NewHash(size)
+	for _, c := range chunks {
+		h.s.absorb(c)
+	}
+	return h.Sum(nil)
+}
+
+// SumMultiFramed returns the size-byte Spritz hash of chunks, framing each one
+// as by Digest.WriteField so that the encoding is injective: different sequences
+// of chunks, including ones with the same concatenation, always hash
+// differently. It is equivalent to calling WriteField with each chunk in order.
+func SumMultiFramed(size int, chunks ...[]byte) []byte {
+	h := NewHash(size)
+	for _, c := range chunks {
+		h.WriteField(c)
+	}
+	return h.Sum(nil)
+}
+
+// KMAC returns outLen bytes of keyed pseudorandom output for message, in the
+// manner of KMAC. Different keys, messages, and output lengths all produce
+// unrelated outputs. The output is squeezed from a state which has absorbed the
+// key, a stop, the label "spritz kmac", a stop, the message, a stop, and outLen
+// as an 8-byte big-endian integer; the label keeps it distinct from Expand.
+func KMAC(key, message []byte, outLen int) []byte {
 	var s state
 	s.initialize(256)
 	s.absorb(key)
 	s.absorbStop()
-	return hasher{size: size, s: &s}
+	s.absorb([]byte("spritz kmac"))
+	s.absorbStop()
+	s.absorb(message)
+	s.absorbStop()
+	absorbUint64(&s, uint64(outLen))
+
+	out := make([]byte, outLen)
+	s.squeeze(out)
+	return out
+}
+
+// sum writes the len(out)-byte Spritz hash of data to out.
+func sum(data, out []byte) {
+	var s state
+	s.initialize(256)
+	s.absorb(data)
+	s.absorbStop()
+	s.absorbByte(len(out))
+	s.squeeze(out)
 }
 
-type hasher struct {
+// Digest is an instance of the Spritz hash or MAC. It implements hash.Hash.
+type Digest struct {
 	size int
-	s    *state
+	n    int
+	key  []byte // nil for unkeyed hashes
+	s    state
+
+	personal  []byte // personalization string, absorbed after the key
+	scratch   state  // reused by SumInto
+	blockSize int    // reported by BlockSize, or zero for the default
 }
 
-func (h hasher) Sum(b []byte) []byte {
-	s := *h.s // make a local copy
-	s.absorbStop()
-	s.absorbByte(h.size)
+// Sum appends the digest of the data written so far to b. It does not change
+// the underlying state.
+func (h *Digest) Sum(b []byte) []byte {
+	ret, out := sliceForAppend(b, h.size)
+	h.SumInto(out)
+	return ret
+}
+
+// SumInto writes the digest of the data written so far to the first Size bytes
+// of dst, without allocating. It does not change the underlying state, and
+// panics if dst is shorter than Size bytes.
+func (h *Digest) SumInto(dst []byte) {
+	if len(dst) < h.size {
+		panic("spritz: output buffer too small")
+	}
+	h.sum(dst[:h.size])
+}
 
-	out := make([]byte, h.size)
+// SumN appends a size-byte digest of the data written so far to b, exactly as
+// Sum would for a hash created with that output size. It does not change the
+// underlying state or the hash's configured size.
+func (h *Digest) SumN(b []byte, size int) []byte {
+	ret, out := sliceForAppend(b, size)
+	h.sum(out)
+	return ret
+}
+
+// sum writes the len(out)-byte digest of the data written so far to out.
+func (h *Digest) sum(out []byte) {
+	s := &h.scratch
+	h.s.copyTo(s) // make a local copy
+	s.absorbStop()
+	s.absorbByte(len(out))
 	s.squeeze(out)
+}
+
+// Verify reports whether tag is the digest of the data written so far,
+// comparing them in constant time. It is meant for checking a MAC over streamed
+// data without calling Sum and comparing the result by hand. Like Sum, it does
+// not change the underlying state.
+func (h *Digest) Verify(tag []byte) bool {
+	if len(tag) != h.size {
+		return false
+	}
 
-	return append(b, out...)
+	actual := make([]byte, h.size)
+	h.sum(actual)
+	return Equal(actual, tag)
 }
 
-func (h hasher) Write(p []byte) (int, error) {
+// XOF returns an io.Reader which produces an unbounded stream of output derived
+// from the data written so far, for use as an extendable-output function. It
+// does not change the underlying state. Where Sum finalizes by absorbing a stop
+// followed by the output size, XOF absorbs two consecutive stops, so its output
+// is unrelated to any fixed-size digest of the same data.
+func (h *Digest) XOF() io.Reader {
+	s := h.s.clone()
+	s.absorbStop()
+	s.absorbStop()
+	return reader{s: s}
+}
+
+// XOFAt returns an io.ReaderAt over the same unbounded output as XOF, for
+// random access to derived key material. Each call to ReadAt recomputes the
+// output from its start, since Spritz cannot seek in closed form, so a read at
+// offset off costs as much as reading off bytes from XOF; reads of the same
+// offsets always agree. ReadAt fills p unless off is negative. Like XOF, it does
+// not change the underlying state.
+func (h *Digest) XOFAt() io.ReaderAt {
+	s := h.s.clone()
+	s.absorbStop()
+	s.absorbStop()
+	return readerAt{s: s}
+}
+
+type readerAt struct {
+	s *state
+}
+
+func (r readerAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errNegOffset
+	}
+
+	s := r.s.clone()
+	for ; off > 0; off-- {
+		s.drip()
+	}
+	s.squeeze(p)
+	return len(p), nil
+}
+
+// Write absorbs p into the hash. It never returns an error.
+func (h *Digest) Write(p []byte) (int, error) {
 	h.s.absorb(p)
 	return len(p), nil
 }
 
-func (h hasher) Size() int {
+// WriteField absorbs a single field of a structured input, framed so that
+// field boundaries are unambiguous: writing the fields "ab" and "c" produces a
+// different digest than writing "a" and "bc". Each field is absorbed as its
+// length, encoded as an 8-byte big-endian integer, followed by its contents.
+func (h *Digest) WriteField(field []byte) {
+	var length [8]byte
+	binary.BigEndian.PutUint64(length[:], uint64(len(field)))
+	h.s.absorb(length[:])
+	h.s.absorb(field)
+}
+
+// WriteUvarint absorbs x in the unsigned varint encoding of encoding/binary:
+// seven bits per byte, least significant group first, with the high bit of each
+// byte set if more bytes follow. The encoding is canonical, so each value has
+// exactly one encoding of one to ten bytes.
+func (h *Digest) WriteUvarint(x uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	h.s.absorb(buf[:binary.PutUvarint(buf[:], x)])
+}
+
+// WriteVarint absorbs x in the signed varint encoding of encoding/binary, which
+// is the unsigned varint encoding of the zig-zag mapping of x: non-negative
+// values v become 2v, and negative values v become -2v-1.
+func (h *Digest) WriteVarint(x int64) {
+	var buf [binary.MaxVarintLen64]byte
+	h.s.absorb(buf[:binary.PutVarint(buf[:], x)])
+}
+
+// WriteByte absorbs c into the hash. It never returns an error.
+func (h *Digest) WriteByte(c byte) error {
+	h.s.absorbByte(int(c))
+	return nil
+}
+
+// ReadFrom absorbs everything read from r until EOF, and returns the number of
+// bytes absorbed. Any error other than EOF is returned.
+func (h *Digest) ReadFrom(r io.Reader) (int64, error) {
+	return h.s.absorbFrom(r)
+}
+
+// Clone returns an independent copy of the hash, including all data written to
+// it so far.
+func (h *Digest) Clone() *Digest {
+	c := *h
+	c.s = *h.s.clone()
+	c.scratch = state{}
+	if h.key != nil {
+		c.key = append([]byte{}, h.key...)
+	}
+	return &c
+}
+
+// Equal reports whether h and other are in identical states: the same output
+// size, state size, MAC key, personalization string, and sponge state, so
+// that any sequence of writes would produce the same digests from both. It
+// runs in constant time for hashes of the same configuration.
+func (h *Digest) Equal(other *Digest) bool {
+	a, _ := h.MarshalBinary()
+	b, _ := other.MarshalBinary()
+	return Equal(a, b)
+}
+
+// Checkpoint saves the hash's current state and returns a function which
+// restores the hash to it, discarding anything written since. It is meant for
+// hashing many messages which share a common prefix: write the prefix, take a
+// checkpoint, and restore it before each message instead of writing the prefix
+// again. Restoring copies the saved state into the hash's existing memory, so
+// unlike Clone it does not allocate, and a checkpoint can be restored any
+// number of times.
+func (h *Digest) Checkpoint() func() {
+	saved := h.s.clone()
+	return func() {
+		saved.copyTo(&h.s)
+	}
+}
+
+// Size returns the number of bytes Sum will append.
+func (h *Digest) Size() int {
 	return h.size
 }
 
-func (h hasher) Reset() {
-	h.s.initialize(256)
+// Reset returns the hash to its initial state.
+func (h *Digest) Reset() {
+	h.s.initialize(h.n)
+	if h.key != nil {
+		h.s.absorb(h.key)
+		h.s.absorbStop()
+	}
+	if len(h.personal) > 0 {
+		h.s.absorbPersonal(h.personal)
+	}
+}
+
+// absorbPersonal absorbs a personalization string between two stops. A MAC key
+// is absorbed with no stop before it, so the two can never produce the same
+// state.
+func (s *state) absorbPersonal(personal []byte) {
+	s.absorbStop()
+	s.absorb(personal)
+	s.absorbStop()
 }
 
-func (hasher) BlockSize() int {
+// BlockSize returns the hash's block size, which is one byte unless it has been
+// changed with SetBlockSize.
+func (h *Digest) BlockSize() int {
+	if h.blockSize > 0 {
+		return h.blockSize
+	}
 	return 1 // single byte
 }
+
+// SetBlockSize sets the block size the hash reports, so that block-oriented
+// writers such as bufio batch their writes into larger chunks. Spritz absorbs
+// one byte at a time, so the block size is purely an efficiency hint: it never
+// changes the digest. A size of zero or less restores the default of one byte.
+// The block size is not included in MarshalBinary's encoding.
+func (h *Digest) SetBlockSize(n int) {
+	h.blockSize = n
+}
+
+const (
+	digestMagic   = "spritz\x01"
+	digestVersion = 1
+)
+
+var (
+	errStateMagic   = errors.New("spritz: invalid hash state identifier")
+	errStateVersion = errors.New("spritz: unsupported hash state version")
+)
+
+// MarshalBinary encodes the hash's output size, its MAC key (if any), its
+// personalization string, and the full sponge state, allowing the hash to be
+// resumed later with UnmarshalBinary. The encoding begins with the magic string
+// "spritz\x01" and a version byte, so that UnmarshalBinary can reject state
+// which was not produced by a Digest or was produced by an incompatible version.
+func (h *Digest) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, len(digestMagic)+1+8*(11+h.n)+len(h.key)+len(h.personal))
+	b = append(b, digestMagic...)
+	b = append(b, digestVersion)
+	b = appendUint64(b, h.size)
+	if h.key != nil {
+		b = appendUint64(b, 1)
+	} else {
+		b = appendUint64(b, 0)
+	}
+	b = appendBytes(b, h.key)
+	b = appendBytes(b, h.personal)
+	return appendState(b, &h.s), nil
+}
+
+// UnmarshalBinary restores a hash previously encoded with MarshalBinary,
+// replacing its output size, MAC key, personalization string, and state.
+func (h *Digest) UnmarshalBinary(b []byte) error {
+	if len(b) < len(digestMagic)+1 || string(b[:len(digestMagic)]) != digestMagic {
+		return errStateMagic
+	}
+	if b[len(digestMagic)] != digestVersion {
+		return errStateVersion
+	}
+	b = b[len(digestMagic)+1:]
+
+	size, b, err := consumeUint64(b)
+	if err != nil {
+		return err
+	}
+	if size < 0 {
+		return errStateSize
+	}
+
+	keyed, b, err := consumeUint64(b)
+	if err != nil {
+		return err
+	}
+	key, b, err := consumeBytes(b)
+	if err != nil {
+		return err
+	}
+	if keyed == 0 && len(key) == 0 {
+		key = nil
+	} else if keyed != 1 {
+		return errStateValue
+	}
+
+	personal, b, err := consumeBytes(b)
+	if err != nil {
+		return err
+	}
+
+	s, err := unmarshalState(b)
+	if err != nil {
+		return err
+	}
+
+	h.size, h.n, h.key, h.personal, h.s = size, s.n, key, personal, s
+	return nil
+}
+
+// Wipe zeroes the hash's state and MAC key. After Wipe, the hash must be
+// re-initialized with Reset before it is used again, and a MAC will then behave
+// as if it had been created with an all-zero key of the same length.
+func (h *Digest) Wipe() {
+	h.s.wipe()
+	h.scratch.wipe()
+	for i := range h.key {
+		h.key[i] = 0
+	}
+}
@@ -2,6 +2,12 @@ package spritz_test
 
 import (
 	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/codahale/spritz"
@@ -29,6 +35,873 @@ func TestHash(t *testing.T) {
 	}
 }
 
+func TestHashN(t *testing.T) {
+	msg := []byte("arcfour")
+
+	a := spritz.NewHashN(32, 256)
+	_, _ = a.Write(msg)
+
+	b := spritz.NewHash(32)
+	_, _ = b.Write(msg)
+
+	if !bytes.Equal(a.Sum(nil), b.Sum(nil)) {
+		t.Errorf("Output for N=256 was \n%x\n but expected\n%x", a.Sum(nil), b.Sum(nil))
+	}
+
+	c := spritz.NewHashN(32, 512)
+	_, _ = c.Write(msg)
+	expected := c.Sum(nil)
+
+	if bytes.Equal(expected, b.Sum(nil)) {
+		t.Errorf("N=512 produced the same digest as N=256: %x", expected)
+	}
+
+	c.Reset()
+	_, _ = c.Write(msg)
+	if actual := c.Sum(nil); !bytes.Equal(actual, expected) {
+		t.Errorf("Output for N=512 after Reset was \n%x\n but expected\n%x", actual, expected)
+	}
+}
+
+func TestMAC(t *testing.T) {
+	msg := []byte("hello world")
+
+	a := spritz.NewMAC([]byte("ABC"), 32)
+	_, _ = a.Write(msg)
+	tagA := a.Sum(nil)
+
+	b := spritz.NewMAC([]byte("spam"), 32)
+	_, _ = b.Write(msg)
+	tagB := b.Sum(nil)
+
+	if bytes.Equal(tagA, tagB) {
+		t.Errorf("Different keys produced the same tag: %x", tagA)
+	}
+
+	h := spritz.NewHash(32)
+	_, _ = h.Write(msg)
+	if bytes.Equal(tagA, h.Sum(nil)) {
+		t.Errorf("MAC produced the same output as the unkeyed hash: %x", tagA)
+	}
+}
+
+func TestMACVerify(t *testing.T) {
+	key, msg := []byte("arcfour"), []byte("hello world")
+
+	h := spritz.NewMAC(key, 32)
+	_, _ = h.Write(msg)
+	tag := h.Sum(nil)
+
+	v := spritz.NewMAC(key, 32)
+	for _, c := range msg {
+		_ = v.WriteByte(c)
+	}
+	if !v.Verify(tag) {
+		t.Error("Couldn't verify a valid tag")
+	}
+
+	modified := append([]byte(nil), tag...)
+	modified[0] ^= 1
+	if v.Verify(modified) {
+		t.Error("Verified a modified tag")
+	}
+
+	if v.Verify(tag[:16]) {
+		t.Error("Verified a truncated tag")
+	}
+
+	tampered := spritz.NewMAC(key, 32)
+	_, _ = tampered.Write([]byte("hello worle"))
+	if tampered.Verify(tag) {
+		t.Error("Verified a tag for a tampered message")
+	}
+}
+
+func TestMACSumIsIdempotent(t *testing.T) {
+	h := spritz.NewMAC([]byte("arcfour"), 32)
+	_, _ = h.Write([]byte("hello world"))
+
+	a := h.Sum(nil)
+	b := h.Sum(nil)
+	if !bytes.Equal(a, b) {
+		t.Errorf("Second Sum was \n%x\n but expected\n%x", b, a)
+	}
+}
+
+func TestHashSumThenWrite(t *testing.T) {
+	for _, n := range []int{256, 17} {
+		h := spritz.NewHashN(32, n)
+		_, _ = h.Write([]byte("hello"))
+		first := h.Sum(nil)
+
+		_, _ = h.Write([]byte(" world"))
+		second := h.Sum(nil)
+
+		fresh := spritz.NewHashN(32, n)
+		_, _ = fresh.Write([]byte("hello world"))
+		if expected := fresh.Sum(nil); !bytes.Equal(second, expected) {
+			t.Errorf("Second Sum for N=%d was \n%x\n but expected\n%x", n, second, expected)
+		}
+
+		fresh.Reset()
+		_, _ = fresh.Write([]byte("hello"))
+		if expected := fresh.Sum(nil); !bytes.Equal(first, expected) {
+			t.Errorf("First Sum for N=%d was \n%x\n but expected\n%x", n, first, expected)
+		}
+	}
+}
+
+func TestHashSumDoesNotModifyState(t *testing.T) {
+	for _, h := range []*spritz.Digest{spritz.NewHash(32), spritz.NewMAC([]byte("arcfour"), 32)} {
+		_, _ = h.Write([]byte("hello"))
+		before, _ := h.MarshalBinary()
+
+		h.Sum(nil)
+		h.SumInto(make([]byte, 32))
+		h.SumN(nil, 64)
+
+		if after, _ := h.MarshalBinary(); !bytes.Equal(after, before) {
+			t.Errorf("Sum modified the hash's state:\n%x\n but expected\n%x", after, before)
+		}
+
+		expected := h.Clone()
+		_, _ = expected.Write([]byte(" world"))
+		_, _ = h.Write([]byte(" world"))
+		if !bytes.Equal(h.Sum(nil), expected.Sum(nil)) {
+			t.Errorf("Output after Sum and Write was \n%x\n but expected\n%x", h.Sum(nil), expected.Sum(nil))
+		}
+	}
+}
+
+func TestMACReset(t *testing.T) {
+	h := spritz.NewMAC([]byte("arcfour"), 32)
+	_, _ = h.Write([]byte("hello world"))
+	expected := h.Sum(nil)
+
+	_, _ = h.Write([]byte("more data"))
+	h.Reset()
+	_, _ = h.Write([]byte("hello world"))
+	actual := h.Sum(nil)
+
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("Output after Reset was \n%x\n but expected\n%x", actual, expected)
+	}
+}
+
+func TestHashMarshalBinary(t *testing.T) {
+	fixtures := []struct {
+		name string
+		new  func() *spritz.Digest
+	}{
+		{"hash", func() *spritz.Digest { return spritz.NewHash(32) }},
+		{"hash N=512", func() *spritz.Digest { return spritz.NewHashN(32, 512) }},
+		{"MAC", func() *spritz.Digest { return spritz.NewMAC([]byte("arcfour"), 32) }},
+		{"personalized", func() *spritz.Digest { return spritz.NewHashPersonalized(32, []byte("test")) }},
+	}
+
+	for _, f := range fixtures {
+		h := f.new()
+		_, _ = h.Write([]byte("hello "))
+
+		state, err := h.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := spritz.NewHash(16)
+		if err := r.UnmarshalBinary(state); err != nil {
+			t.Fatalf("Couldn't unmarshal %s: %v", f.name, err)
+		}
+
+		_, _ = h.Write([]byte("world"))
+		_, _ = r.Write([]byte("world"))
+
+		if !bytes.Equal(r.Sum(nil), h.Sum(nil)) {
+			t.Errorf("Output for restored %s was \n%x\n but expected\n%x", f.name, r.Sum(nil), h.Sum(nil))
+		}
+
+		expected := f.new()
+		_, _ = expected.Write([]byte("hello world"))
+		r.Reset()
+		_, _ = r.Write([]byte("hello world"))
+
+		if !bytes.Equal(r.Sum(nil), expected.Sum(nil)) {
+			t.Errorf("Output for reset %s was \n%x\n but expected\n%x", f.name, r.Sum(nil), expected.Sum(nil))
+		}
+	}
+}
+
+func TestHashUnmarshalBinaryCorrupt(t *testing.T) {
+	h := spritz.NewHash(32)
+	_, _ = h.Write([]byte("hello world"))
+	state, err := h.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	duplicate := append([]byte(nil), state...)
+	copy(duplicate[len(duplicate)-8:], duplicate[len(duplicate)-16:len(duplicate)-8])
+
+	smallN := append([]byte(nil), state...)
+	smallN[len(smallN)-8*(7+256)+7] = 8
+	smallN[len(smallN)-8*(7+256)+6] = 0
+
+	fixtures := []struct {
+		name  string
+		state []byte
+	}{
+		{"empty", nil},
+		{"truncated", state[:len(state)-1]},
+		{"extended", append(append([]byte(nil), state...), 0)},
+		{"small N", smallN},
+		{"duplicate permutation entry", duplicate},
+	}
+
+	for _, f := range fixtures {
+		err := spritz.NewHash(32).UnmarshalBinary(f.state)
+		if err == nil {
+			t.Errorf("Unmarshaled %s state", f.name)
+		}
+	}
+}
+
+func TestHashUnmarshalBinaryErrors(t *testing.T) {
+	h := spritz.NewHash(32)
+	state, err := h.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	smallN := append([]byte(nil), state...)
+	smallN[len(smallN)-8*(7+256)+7] = 8
+	smallN[len(smallN)-8*(7+256)+6] = 0
+
+	fixtures := []struct {
+		name     string
+		state    []byte
+		expected error
+	}{
+		{"truncated header", state[:12], spritz.ErrShortBuffer},
+		{"truncated", state[:len(state)-1], spritz.ErrShortBuffer},
+		{"small N", smallN, spritz.ErrInvalidN},
+	}
+
+	for _, f := range fixtures {
+		if err := spritz.NewHash(32).UnmarshalBinary(f.state); !errors.Is(err, f.expected) {
+			t.Errorf("Unmarshaling %s state returned %v but expected %v", f.name, err, f.expected)
+		}
+	}
+}
+
+func TestHashUnmarshalBinaryForeign(t *testing.T) {
+	h := spritz.NewHash(32)
+	state, err := h.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if prefix := []byte("spritz\x01\x01"); !bytes.HasPrefix(state, prefix) {
+		t.Errorf("State began with %x but expected %x", state[:len(prefix)], prefix)
+	}
+
+	version := append([]byte(nil), state...)
+	version[7] = 2
+
+	stream, err := spritz.NewStream([]byte("arcfour")).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fixtures := []struct {
+		name  string
+		state []byte
+	}{
+		{"empty", nil},
+		{"unknown version", version},
+		{"stream", stream},
+		{"unprefixed", state[8:]},
+	}
+
+	for _, f := range fixtures {
+		if err := spritz.NewHash(32).UnmarshalBinary(f.state); err == nil {
+			t.Errorf("Unmarshaled %s state", f.name)
+		}
+	}
+}
+
+func TestHashWipe(t *testing.T) {
+	h := spritz.NewMAC([]byte("arcfour"), 32)
+	_, _ = h.Write([]byte("hello world"))
+	h.Wipe()
+
+	state, err := h.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, v := range state[len(state)-8*(6+256):] { // skip all but a..z and the permutation
+		if v != 0 {
+			t.Fatalf("Wiped state has non-zero byte %x at %d", v, i)
+		}
+	}
+
+	h.Reset()
+	_, _ = h.Write([]byte("hello world"))
+
+	expected := spritz.NewMAC(make([]byte, 7), 32)
+	_, _ = expected.Write([]byte("hello world"))
+
+	if !bytes.Equal(h.Sum(nil), expected.Sum(nil)) {
+		t.Errorf("Output after Wipe and Reset was \n%x\n but expected\n%x", h.Sum(nil), expected.Sum(nil))
+	}
+}
+
+func TestSum256(t *testing.T) {
+	for _, msg := range []string{"", "ABC", "spam", "arcfour"} {
+		h := spritz.NewHash(32)
+		_, _ = h.Write([]byte(msg))
+		expected := h.Sum(nil)
+
+		if out := spritz.Sum256([]byte(msg)); !bytes.Equal(out[:], expected) {
+			t.Errorf("Output for %q was \n%x\n but expected\n%x", msg, out, expected)
+		}
+	}
+}
+
+func TestSum512(t *testing.T) {
+	for _, msg := range []string{"", "ABC", "spam", "arcfour"} {
+		h := spritz.NewHash(64)
+		_, _ = h.Write([]byte(msg))
+		expected := h.Sum(nil)
+
+		if out := spritz.Sum512([]byte(msg)); !bytes.Equal(out[:], expected) {
+			t.Errorf("Output for %q was \n%x\n but expected\n%x", msg, out, expected)
+		}
+	}
+}
+
+func TestHashClone(t *testing.T) {
+	a := spritz.NewHash(32)
+	_, _ = a.Write([]byte("prefix "))
+
+	b := a.Clone()
+	_, _ = a.Write([]byte("one"))
+	_, _ = b.Write([]byte("two"))
+
+	if bytes.Equal(a.Sum(nil), b.Sum(nil)) {
+		t.Errorf("Clones with different suffixes produced the same digest: %x", a.Sum(nil))
+	}
+
+	for _, f := range []struct {
+		h   *spritz.Digest
+		msg string
+	}{
+		{a, "prefix one"},
+		{b, "prefix two"},
+	} {
+		expected := spritz.NewHash(32)
+		_, _ = expected.Write([]byte(f.msg))
+
+		if !bytes.Equal(f.h.Sum(nil), expected.Sum(nil)) {
+			t.Errorf("Output for %q was \n%x\n but expected\n%x", f.msg, f.h.Sum(nil), expected.Sum(nil))
+		}
+	}
+}
+
+func TestHashXOF(t *testing.T) {
+	h := spritz.NewHash(32)
+	_, _ = h.Write([]byte("arcfour"))
+
+	all := make([]byte, 100)
+	if _, err := io.ReadFull(h.XOF(), all); err != nil {
+		t.Fatal(err)
+	}
+
+	xof := h.XOF()
+	parts := make([]byte, 100)
+	if _, err := io.ReadFull(xof, parts[:37]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(xof, parts[37:]); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(parts, all) {
+		t.Errorf("Output in parts was \n%x\n but expected\n%x", parts, all)
+	}
+
+	if bytes.Equal(all[:32], h.Sum(nil)) {
+		t.Errorf("XOF output matched Sum: %x", h.Sum(nil))
+	}
+}
+
+func TestHashXOFAt(t *testing.T) {
+	h := spritz.NewHash(32)
+	_, _ = h.Write([]byte("arcfour"))
+
+	all := make([]byte, 100)
+	if _, err := io.ReadFull(h.XOF(), all); err != nil {
+		t.Fatal(err)
+	}
+
+	r := h.XOFAt()
+	for _, f := range []struct{ off, n int }{{0, 100}, {0, 10}, {37, 20}, {50, 50}, {99, 1}} {
+		out := make([]byte, f.n)
+		if n, err := r.ReadAt(out, int64(f.off)); n != f.n || err != nil {
+			t.Fatalf("ReadAt returned %d, %v", n, err)
+		}
+
+		if expected := all[f.off : f.off+f.n]; !bytes.Equal(out, expected) {
+			t.Errorf("Output at %d was \n%x\n but expected\n%x", f.off, out, expected)
+		}
+	}
+
+	if _, err := r.ReadAt(make([]byte, 1), -1); err == nil {
+		t.Error("Read at a negative offset")
+	}
+}
+
+func TestHashCheckpoint(t *testing.T) {
+	h := spritz.NewMAC([]byte("arcfour"), 32)
+	_, _ = h.Write([]byte("prefix "))
+	restore := h.Checkpoint()
+
+	for _, msg := range []string{"one", "two", "three"} {
+		restore()
+		_, _ = h.Write([]byte(msg))
+
+		expected := spritz.NewMAC([]byte("arcfour"), 32)
+		_, _ = expected.Write([]byte("prefix " + msg))
+
+		if !bytes.Equal(h.Sum(nil), expected.Sum(nil)) {
+			t.Errorf("Output for %q was \n%x\n but expected\n%x", msg, h.Sum(nil), expected.Sum(nil))
+		}
+	}
+
+	if allocs := testing.AllocsPerRun(10, restore); allocs != 0 {
+		t.Errorf("Restoring a checkpoint allocated %v times", allocs)
+	}
+}
+
+func TestHashPersonalized(t *testing.T) {
+	msg := []byte("arcfour")
+
+	a := spritz.NewHashPersonalized(32, []byte("protocol A"))
+	_, _ = a.Write(msg)
+
+	b := spritz.NewHashPersonalized(32, []byte("protocol B"))
+	_, _ = b.Write(msg)
+
+	if bytes.Equal(a.Sum(nil), b.Sum(nil)) {
+		t.Errorf("Different personalizations produced the same digest: %x", a.Sum(nil))
+	}
+
+	mac := spritz.NewMAC([]byte("protocol A"), 32)
+	_, _ = mac.Write(msg)
+
+	if bytes.Equal(a.Sum(nil), mac.Sum(nil)) {
+		t.Errorf("Personalization produced the digest of a MAC: %x", a.Sum(nil))
+	}
+
+	c := spritz.NewHashPersonalized(32, nil)
+	_, _ = c.Write(msg)
+
+	d := spritz.NewHash(32)
+	_, _ = d.Write(msg)
+
+	if !bytes.Equal(c.Sum(nil), d.Sum(nil)) {
+		t.Errorf("Output for empty personalization was \n%x\n but expected\n%x", c.Sum(nil), d.Sum(nil))
+	}
+
+	expected := a.Sum(nil)
+	a.Reset()
+	_, _ = a.Write(msg)
+	if !bytes.Equal(a.Sum(nil), expected) {
+		t.Errorf("Output after Reset was \n%x\n but expected\n%x", a.Sum(nil), expected)
+	}
+}
+
+func TestHashWriteByte(t *testing.T) {
+	msg := []byte("arcfour")
+
+	a := spritz.NewHash(32)
+	for _, c := range msg {
+		_ = a.WriteByte(c)
+	}
+
+	b := spritz.NewHash(32)
+	_, _ = b.Write(msg)
+
+	if !bytes.Equal(a.Sum(nil), b.Sum(nil)) {
+		t.Errorf("Output was \n%x\n but expected\n%x", a.Sum(nil), b.Sum(nil))
+	}
+}
+
+func TestHashWriteDoesNotAllocate(t *testing.T) {
+	h := spritz.NewHash(32)
+	in := make([]byte, 1024)
+
+	if n := testing.AllocsPerRun(10, func() { _, _ = h.Write(in) }); n != 0 {
+		t.Errorf("Write allocated %v times per call", n)
+	}
+}
+
+func TestSumHex(t *testing.T) {
+	fixtures := []struct {
+		msg, prefix string
+	}{
+		// PDF only provides first 8 bytes for a 32-byte hash
+		{"ABC", "028fa2b48b934a18"},
+		{"spam", "acbba0813f300d3a"},
+		{"arcfour", "ff8cf268094c87b9"},
+	}
+
+	for _, f := range fixtures {
+		out := spritz.SumHex([]byte(f.msg), 32)
+		if !strings.HasPrefix(out, f.prefix) {
+			t.Errorf("Output for %q was %s but expected a prefix of %s", f.msg, out, f.prefix)
+		}
+
+		h := spritz.Sum256([]byte(f.msg))
+		if expected := hex.EncodeToString(h[:]); out != expected {
+			t.Errorf("Output for %q was %s but expected %s", f.msg, out, expected)
+		}
+	}
+}
+
+func TestSumMulti(t *testing.T) {
+	expected := spritz.Sum256([]byte("hello world"))
+
+	if out := spritz.SumMulti(32, []byte("hello"), nil, []byte(" world")); !bytes.Equal(out, expected[:]) {
+		t.Errorf("Output was \n%x\n but expected\n%x", out, expected)
+	}
+
+	a := spritz.SumMultiFramed(32, []byte("ab"), []byte("c"))
+	b := spritz.SumMultiFramed(32, []byte("a"), []byte("bc"))
+	if bytes.Equal(a, b) {
+		t.Errorf("Different framed chunks produced the same digest: %x", a)
+	}
+
+	h := spritz.NewHash(32)
+	h.WriteField([]byte("ab"))
+	h.WriteField([]byte("c"))
+	if !bytes.Equal(a, h.Sum(nil)) {
+		t.Errorf("Framed output was \n%x\n but expected\n%x", a, h.Sum(nil))
+	}
+}
+
+func TestKMAC(t *testing.T) {
+	key, msg := []byte("arcfour"), []byte("hello world")
+
+	s := spritz.NewSponge(256)
+	s.Absorb(key)
+	s.AbsorbStop()
+	s.Absorb([]byte("spritz kmac"))
+	s.AbsorbStop()
+	s.Absorb(msg)
+	s.AbsorbStop()
+	s.Absorb([]byte{0, 0, 0, 0, 0, 0, 0, 32})
+	expected := make([]byte, 32)
+	s.Squeeze(expected)
+
+	out := spritz.KMAC(key, msg, 32)
+	if !bytes.Equal(out, expected) {
+		t.Errorf("Output was \n%x\n but expected\n%x", out, expected)
+	}
+
+	fixtures := []struct {
+		name string
+		out  []byte
+	}{
+		{"key", spritz.KMAC([]byte("spam"), msg, 32)},
+		{"message", spritz.KMAC(key, []byte("hello worle"), 32)},
+		{"output length", spritz.KMAC(key, msg, 64)[:32]},
+		{"construction", spritz.Expand(key, msg, 32)},
+	}
+
+	for _, f := range fixtures {
+		if bytes.Equal(out, f.out) {
+			t.Errorf("A different %s produced the same output: %x", f.name, out)
+		}
+	}
+}
+
+func TestHashSetBlockSize(t *testing.T) {
+	h := spritz.NewHash(32)
+	if bs := h.BlockSize(); bs != 1 {
+		t.Errorf("Default block size was %d", bs)
+	}
+
+	expected := spritz.Sum256([]byte("hello world"))
+
+	h.SetBlockSize(64)
+	if bs := h.BlockSize(); bs != 64 {
+		t.Errorf("Block size was %d but expected 64", bs)
+	}
+
+	_, _ = h.Write([]byte("hello world"))
+	if out := h.Sum(nil); !bytes.Equal(out, expected[:]) {
+		t.Errorf("Output was \n%x\n but expected\n%x", out, expected)
+	}
+
+	h.SetBlockSize(0)
+	if bs := h.BlockSize(); bs != 1 {
+		t.Errorf("Block size after reset was %d", bs)
+	}
+}
+
+func TestSumReader(t *testing.T) {
+	for _, msg := range []string{"", "ABC", strings.Repeat("arcfour", 10000)} {
+		out, err := spritz.SumReader(strings.NewReader(msg), 32)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected := spritz.Sum256([]byte(msg)); !bytes.Equal(out, expected[:]) {
+			t.Errorf("Output for %d bytes was \n%x\n but expected\n%x", len(msg), out, expected)
+		}
+	}
+}
+
+type errReader struct{}
+
+var errRead = errors.New("read failed")
+
+func (errReader) Read(p []byte) (int, error) {
+	return 0, errRead
+}
+
+func TestSumReaderError(t *testing.T) {
+	r := io.MultiReader(strings.NewReader("hello"), errReader{})
+	if out, err := spritz.SumReader(r, 32); err != errRead {
+		t.Errorf("SumReader returned %x, %v", out, err)
+	}
+}
+
+func TestHashReadFrom(t *testing.T) {
+	msg := strings.Repeat("arcfour", 10000)
+
+	h := spritz.NewHash(32)
+	n, err := io.Copy(h, strings.NewReader(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(msg)) {
+		t.Errorf("Copied %d bytes but expected %d", n, len(msg))
+	}
+
+	if expected := spritz.Sum256([]byte(msg)); !bytes.Equal(h.Sum(nil), expected[:]) {
+		t.Errorf("Output was \n%x\n but expected\n%x", h.Sum(nil), expected)
+	}
+}
+
+func TestHashSumInto(t *testing.T) {
+	h := spritz.NewHash(32)
+	_, _ = h.Write([]byte("arcfour"))
+
+	out := make([]byte, 40)
+	h.SumInto(out)
+
+	if expected := h.Sum(nil); !bytes.Equal(out[:32], expected) {
+		t.Errorf("Output was \n%x\n but expected\n%x", out[:32], expected)
+	}
+
+	if n := testing.AllocsPerRun(10, func() { h.SumInto(out) }); n != 0 {
+		t.Errorf("SumInto allocated %v times per call", n)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("SumInto accepted a short buffer")
+		}
+	}()
+	h.SumInto(out[:31])
+}
+
+func TestHashWriteField(t *testing.T) {
+	a := spritz.NewHash(32)
+	a.WriteField([]byte("ab"))
+	a.WriteField([]byte("c"))
+
+	b := spritz.NewHash(32)
+	b.WriteField([]byte("a"))
+	b.WriteField([]byte("bc"))
+
+	if bytes.Equal(a.Sum(nil), b.Sum(nil)) {
+		t.Errorf("Different field boundaries produced the same digest: %x", a.Sum(nil))
+	}
+
+	c := spritz.NewHash(32)
+	_, _ = c.Write([]byte{0, 0, 0, 0, 0, 0, 0, 2, 'a', 'b', 0, 0, 0, 0, 0, 0, 0, 1, 'c'})
+
+	if !bytes.Equal(a.Sum(nil), c.Sum(nil)) {
+		t.Errorf("Output was \n%x\n but expected\n%x", a.Sum(nil), c.Sum(nil))
+	}
+}
+
+func TestHashWriteVarint(t *testing.T) {
+	fixtures := []struct {
+		write   func(h *spritz.Digest)
+		encoded []byte
+	}{
+		{func(h *spritz.Digest) { h.WriteUvarint(0) }, []byte{0x00}},
+		{func(h *spritz.Digest) { h.WriteUvarint(300) }, []byte{0xac, 0x02}},
+		{func(h *spritz.Digest) { h.WriteUvarint(1<<64 - 1) }, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}},
+		{func(h *spritz.Digest) { h.WriteVarint(-1) }, []byte{0x01}},
+		{func(h *spritz.Digest) { h.WriteVarint(150) }, []byte{0xac, 0x02}},
+	}
+
+	for _, f := range fixtures {
+		a := spritz.NewHash(32)
+		f.write(a)
+
+		b := spritz.NewHash(32)
+		_, _ = b.Write(f.encoded)
+
+		if !bytes.Equal(a.Sum(nil), b.Sum(nil)) {
+			t.Errorf("Output for %x was \n%x\n but expected\n%x", f.encoded, a.Sum(nil), b.Sum(nil))
+		}
+	}
+}
+
+func TestHashSumN(t *testing.T) {
+	msg := []byte("arcfour")
+	h := spritz.NewHash(32)
+	_, _ = h.Write(msg)
+
+	for _, size := range []int{8, 64, 32, 16} {
+		expected := spritz.NewHash(size)
+		_, _ = expected.Write(msg)
+
+		if out := h.SumN(nil, size); !bytes.Equal(out, expected.Sum(nil)) {
+			t.Errorf("Output for size=%d was \n%x\n but expected\n%x", size, out, expected.Sum(nil))
+		}
+	}
+
+	if h.Size() != 32 {
+		t.Errorf("Size was %d but expected 32", h.Size())
+	}
+
+	_, _ = h.Write(msg)
+	expected := spritz.NewHash(32)
+	_, _ = expected.Write(append(msg, msg...))
+
+	if !bytes.Equal(h.Sum(nil), expected.Sum(nil)) {
+		t.Errorf("Output after SumN and Write was \n%x\n but expected\n%x", h.Sum(nil), expected.Sum(nil))
+	}
+}
+
+func TestHashEqual(t *testing.T) {
+	a := spritz.NewHash(32)
+	_, _ = a.Write([]byte("arcfour"))
+
+	b := a.Clone()
+	if !a.Equal(b) {
+		t.Error("A clone was not equal to its original")
+	}
+
+	_, _ = b.Write([]byte("!"))
+	if a.Equal(b) {
+		t.Error("Hashes of different data were equal")
+	}
+
+	fixtures := []struct {
+		name string
+		h    *spritz.Digest
+	}{
+		{"output size", spritz.NewHash(64)},
+		{"state size", spritz.NewHashN(32, 257)},
+		{"key", spritz.NewMAC([]byte("key"), 32)},
+		{"personalization", spritz.NewHashPersonalized(32, []byte("app"))},
+	}
+
+	for _, f := range fixtures {
+		_, _ = f.h.Write([]byte("arcfour"))
+		if a.Equal(f.h) {
+			t.Errorf("Hashes with a different %s were equal", f.name)
+		}
+	}
+}
+
+func TestSumFile(t *testing.T) {
+	msg := []byte(strings.Repeat("arcfour", 10000))
+	path := filepath.Join(t.TempDir(), "msg")
+	if err := os.WriteFile(path, msg, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := spritz.SumFile(path, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := spritz.Sum256(msg); !bytes.Equal(out, expected[:]) {
+		t.Errorf("Output was \n%x\n but expected\n%x", out, expected)
+	}
+}
+
+func TestSumFileErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	fixtures := []struct {
+		name, path, op string
+		target         error
+	}{
+		{"a missing file", filepath.Join(dir, "missing"), "opening", os.ErrNotExist},
+		{"a directory", dir, "reading", nil},
+	}
+
+	for _, f := range fixtures {
+		out, err := spritz.SumFile(f.path, 32)
+		if err == nil || out != nil {
+			t.Errorf("Hashed %s: %x", f.name, out)
+			continue
+		}
+		if !strings.Contains(err.Error(), f.op) {
+			t.Errorf("Error for %s was %q but expected it to mention %s", f.name, err, f.op)
+		}
+		if f.target != nil && !errors.Is(err, f.target) {
+			t.Errorf("Error for %s did not wrap %v: %v", f.name, f.target, err)
+		}
+	}
+}
+
+func TestHashEmpty(t *testing.T) {
+	// computed from the paper's pseudocode, which gives no empty-message vectors
+	fixtures := []struct {
+		size     int
+		expected string
+	}{
+		{1, "70"},
+		{16, "dee2b6e00fada570e614d81921289202"},
+		{32, "eddbfc9e608c1a73eb8d1311c483626104b8ea762d3075768af586838ffb0381"},
+		{64, "88d15cc9b3f264f3a097e01789115a33367cfc20d8f7f5273d3a1842142734cf2d20d1eb1780f4021347e9fac8aa3abe245ac3cea3b0633a0acb169e5ddc8458"},
+	}
+
+	for _, f := range fixtures {
+		expected, _ := hex.DecodeString(f.expected)
+
+		h := spritz.NewHash(f.size)
+		if out := h.Sum(nil); !bytes.Equal(out, expected) {
+			t.Errorf("Output for size %d was \n%x\n but expected\n%x", f.size, out, expected)
+		}
+
+		h.Reset()
+		_, _ = h.Write(nil)
+		if out := h.Sum(nil); !bytes.Equal(out, expected) {
+			t.Errorf("Output for size %d after an empty write was \n%x\n but expected\n%x", f.size, out, expected)
+		}
+
+		if out := spritz.NewHash(32).SumN(nil, f.size); !bytes.Equal(out, expected) {
+			t.Errorf("SumN output for size %d was \n%x\n but expected\n%x", f.size, out, expected)
+		}
+	}
+
+	if out := spritz.Sum256(nil); hex.EncodeToString(out[:]) != fixtures[2].expected {
+		t.Errorf("Sum256 output was \n%x\n but expected\n%s", out, fixtures[2].expected)
+	}
+}
+
 func BenchmarkHash(b *testing.B) {
 	h := spritz.NewHash(32)
 	out := make([]byte, 1024)
@@ -40,3 +913,25 @@ func BenchmarkHash(b *testing.B) {
 		_, _ = h.Write(out)
 	}
 }
+
+func BenchmarkSum256(b *testing.B) {
+	in := make([]byte, 1024)
+	b.SetBytes(int64(len(in)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		spritz.Sum256(in)
+	}
+}
+
+func BenchmarkHashWriteByte(b *testing.B) {
+	h := spritz.NewHash(32)
+	b.SetBytes(1)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_ = h.WriteByte(byte(i))
+	}
+}
@@ -0,0 +1,23 @@
+package spritz
+
+import (
+	"crypto/hmac"
+	"hash"
+)
+
+// HMACBlockSize is the block size, in bytes, used for the HMAC padding by
+// NewHMAC.
+const HMACBlockSize = 64
+
+// NewHMAC returns a new HMAC, as defined in RFC 2104, over the Spritz hash with
+// the given key and output size. It is only meant for protocols which mandate
+// HMAC, since NewMAC is simpler and faster. Spritz has no natural block size,
+// so the key is padded to HMACBlockSize bytes, and keys longer than that are
+// first hashed with NewHash(size).
+func NewHMAC(key []byte, size int) hash.Hash {
+	return hmac.New(func() hash.Hash {
+		h := NewHash(size)
+		h.SetBlockSize(HMACBlockSize)
+		return h
+	}, key)
+}
@@ -0,0 +1,63 @@
+package spritz_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/codahale/spritz"
+)
+
+// hmac computes HMAC from its definition: two passes of the hash, keyed by
+// the padded key XORed with the inner and outer pads.
+func hmac(key, msg []byte, size int) []byte {
+	if len(key) > spritz.HMACBlockSize {
+		h := spritz.NewHash(size)
+		_, _ = h.Write(key)
+		key = h.Sum(nil)
+	}
+	ipad := make([]byte, spritz.HMACBlockSize)
+	opad := make([]byte, spritz.HMACBlockSize)
+	copy(ipad, key)
+	copy(opad, key)
+	for i := range ipad {
+		ipad[i] ^= 0x36
+		opad[i] ^= 0x5c
+	}
+
+	inner := spritz.NewHash(size)
+	_, _ = inner.Write(ipad)
+	_, _ = inner.Write(msg)
+
+	outer := spritz.NewHash(size)
+	_, _ = outer.Write(opad)
+	_, _ = outer.Write(inner.Sum(nil))
+	return outer.Sum(nil)
+}
+
+func TestHMAC(t *testing.T) {
+	msg := []byte("hello world")
+	for _, key := range []string{"", "arcfour", strings.Repeat("k", spritz.HMACBlockSize), strings.Repeat("k", 100)} {
+		h := spritz.NewHMAC([]byte(key), 32)
+		_, _ = h.Write(msg)
+
+		if out, expected := h.Sum(nil), hmac([]byte(key), msg, 32); !bytes.Equal(out, expected) {
+			t.Errorf("Output for a %d-byte key was \n%x\n but expected\n%x", len(key), out, expected)
+		}
+	}
+}
+
+func TestHMACSizes(t *testing.T) {
+	h := spritz.NewHMAC([]byte("arcfour"), 20)
+	if v := h.Size(); v != 20 {
+		t.Errorf("Size was %d but expected 20", v)
+	}
+	if v := h.BlockSize(); v != spritz.HMACBlockSize {
+		t.Errorf("BlockSize was %d but expected %d", v, spritz.HMACBlockSize)
+	}
+
+	mac := spritz.NewMAC([]byte("arcfour"), 20)
+	if bytes.Equal(h.Sum(nil), mac.Sum(nil)) {
+		t.Error("HMAC was the same as the native MAC")
+	}
+}
@@ -0,0 +1,78 @@
+package spritz
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+)
+
+// KeySize is the recommended size, in bytes, of Spritz keys.
+const KeySize = 32
+
+var errKeySize = errors.New("spritz: key size must be positive")
+
+// GenerateKey returns a new n-byte key read from crypto/rand, or an error if n
+// is not positive or the system's random source fails. Most callers should use
+// a size of KeySize.
+func GenerateKey(n int) ([]byte, error) {
+	if n <= 0 {
+		return nil, errKeySize
+	}
+
+	key := make([]byte, n)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// WeakKey reports whether key is obviously unsafe to use: empty, or made up of a
+// single repeated byte, such as an all-zero key. It is a heuristic guard against
+// placeholder and test keys reaching production, not a measure of a key's
+// strength, and keys which pass it are not necessarily safe; keys should be
+// generated with GenerateKey or another cryptographically secure source.
+func WeakKey(key []byte) bool {
+	for _, b := range key {
+		if b != key[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// checkKey returns ErrEmptyKey if key is empty, or ErrWeakKey if it is weak.
+func checkKey(key []byte) error {
+	if len(key) == 0 {
+		return ErrEmptyKey
+	}
+	if WeakKey(key) {
+		return ErrWeakKey
+	}
+	return nil
+}
+
+// Expand returns length bytes of key material derived from key and info, in the
+// manner of HKDF-Expand, for deriving several independent subkeys from a single
+// master key. Different info labels produce unrelated outputs, as do different
+// lengths. The output is squeezed from a state which has absorbed key, a stop,
+// info, a stop, and length as an 8-byte big-endian integer.
+func Expand(key, info []byte, length int) []byte {
+	out := make([]byte, length)
+	expand(key, info, out)
+	return out
+}
+
+// expand fills out with key material derived from key and info.
+func expand(key, info, out []byte) {
+	var s state
+	s.initialize(256)
+	s.absorb(key)
+	s.absorbStop()
+	s.absorb(info)
+	s.absorbStop()
+
+	var length [8]byte
+	binary.BigEndian.PutUint64(length[:], uint64(len(out)))
+	s.absorb(length[:])
+	s.squeeze(out)
+}
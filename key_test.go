@@ -0,0 +1,88 @@
+package spritz_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/codahale/spritz"
+)
+
+func TestGenerateKey(t *testing.T) {
+	a, err := spritz.GenerateKey(spritz.KeySize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := spritz.GenerateKey(spritz.KeySize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(a) != spritz.KeySize {
+		t.Errorf("Key was %d bytes but expected %d", len(a), spritz.KeySize)
+	}
+
+	if bytes.Equal(a, b) {
+		t.Errorf("Generated the same key twice: %x", a)
+	}
+
+	for _, n := range []int{0, -1} {
+		if _, err := spritz.GenerateKey(n); err == nil {
+			t.Errorf("Generated a key of size %d", n)
+		}
+	}
+}
+
+func TestWeakKey(t *testing.T) {
+	fixtures := []struct {
+		key  []byte
+		weak bool
+	}{
+		{nil, true},
+		{make([]byte, 32), true},
+		{bytes.Repeat([]byte{0xaa}, 16), true},
+		{[]byte("a"), true},
+		{[]byte("arcfour"), false},
+		{append(make([]byte, 31), 1), false},
+	}
+
+	for _, f := range fixtures {
+		if weak := spritz.WeakKey(f.key); weak != f.weak {
+			t.Errorf("WeakKey(%x) was %v but expected %v", f.key, weak, f.weak)
+		}
+	}
+
+	if _, err := spritz.NewStreamChecked(make([]byte, 32)); err != spritz.ErrWeakKey {
+		t.Errorf("Created a checked stream with an all-zero key: %v", err)
+	}
+
+	if _, err := spritz.NewStreamNChecked(make([]byte, 32), 256); err != spritz.ErrWeakKey {
+		t.Errorf("Created a checked stream with an all-zero key: %v", err)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	key := []byte("arcfour")
+
+	s := spritz.NewSponge(256)
+	s.Absorb(key)
+	s.AbsorbStop()
+	s.Absorb([]byte("encryption"))
+	s.AbsorbStop()
+	s.Absorb([]byte{0, 0, 0, 0, 0, 0, 0, 32})
+	expected := make([]byte, 32)
+	s.Squeeze(expected)
+
+	enc := spritz.Expand(key, []byte("encryption"), 32)
+	if !bytes.Equal(enc, expected) {
+		t.Errorf("Output was \n%x\n but expected\n%x", enc, expected)
+	}
+
+	if mac := spritz.Expand(key, []byte("authentication"), 32); bytes.Equal(enc, mac) {
+		t.Errorf("Different labels produced the same output: %x", enc)
+	}
+
+	if long := spritz.Expand(key, []byte("encryption"), 64); bytes.Equal(enc, long[:32]) {
+		t.Errorf("Different lengths produced the same output: %x", enc)
+	}
+}
@@ -0,0 +1,97 @@
+package spritz
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	errStateLength = errors.New("spritz: invalid state length")
+	errStateSize   = errors.New("spritz: invalid state size")
+	errStateValue  = errors.New("spritz: invalid state")
+)
+
+// appendState appends the encoding of s to b.
+func appendState(b []byte, s *state) []byte {
+	b = appendUint64(b, s.n)
+	for _, v := range []int{s.a, s.i, s.j, s.k, s.w, s.z} {
+		b = appendUint64(b, v)
+	}
+	for _, v := range s.s {
+		b = appendUint64(b, v)
+	}
+	return b
+}
+
+// unmarshalState decodes a state encoded with appendState, which must make up
+// all of b.
+func unmarshalState(b []byte) (state, error) {
+	var s state
+
+	n, b, err := consumeUint64(b)
+	if err != nil {
+		return s, err
+	}
+	if n < minN {
+		return s, ErrInvalidN
+	}
+	if n > len(b)/8 || len(b) < 8*(6+n) {
+		return s, ErrShortBuffer
+	}
+	if len(b) != 8*(6+n) {
+		return s, errStateLength
+	}
+
+	s.initialize(n)
+	for _, v := range []*int{&s.a, &s.i, &s.j, &s.k, &s.w, &s.z} {
+		*v, b, _ = consumeUint64(b)
+		if *v < 0 || *v >= n {
+			return s, errStateValue
+		}
+	}
+
+	// the permutation must contain each value in [0, n) exactly once
+	seen := make([]bool, n)
+	for i := range s.s {
+		s.s[i], b, _ = consumeUint64(b)
+		if s.s[i] < 0 || s.s[i] >= n || seen[s.s[i]] {
+			return s, errStateValue
+		}
+		seen[s.s[i]] = true
+	}
+	return s, nil
+}
+
+func appendUint64(b []byte, v int) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(v))
+	return append(b, buf[:]...)
+}
+
+func consumeUint64(b []byte) (int, []byte, error) {
+	if len(b) < 8 {
+		return 0, b, ErrShortBuffer
+	}
+	return int(binary.BigEndian.Uint64(b)), b[8:], nil
+}
+
+// appendBytes appends the length of v followed by v to b.
+func appendBytes(b, v []byte) []byte {
+	b = appendUint64(b, len(v))
+	return append(b, v...)
+}
+
+// consumeBytes decodes a copy of a slice encoded with appendBytes.
+func consumeBytes(b []byte) ([]byte, []byte, error) {
+	n, b, err := consumeUint64(b)
+	if err != nil {
+		return nil, b, err
+	}
+	if n < 0 {
+		return nil, b, errStateValue
+	}
+	if n > len(b) {
+		return nil, b, ErrShortBuffer
+	}
+	return append([]byte{}, b[:n]...), b[n:], nil
+}
@@ -1,5 +1,7 @@
 package spritz
 
+import "encoding/binary"
+
 // InsecurePasswordHash calculates a CPU- and memory-hard hash of the given
 // password and salt. It takes two exponential parameters, M and T, which
 // determine the memory and CPU costs. It also takes the length of the hash in
@@ -34,3 +36,37 @@ func InsecurePasswordHash(password, salt []byte, m, t, n uint) []byte {
 	s.squeeze(out)
 	return out
 }
+
+// DeriveKey derives a key of keyLen bytes from the given password and salt. It
+// repeatedly absorbs the password, the salt, and an iteration counter into a
+// single state and squeezes output from it, so the work factor is linear in the
+// number of iterations, which must be at least one.
+func DeriveKey(password, salt []byte, iterations, keyLen int) []byte {
+	if iterations < 1 {
+		panic("spritz: iterations must be at least one")
+	}
+
+	var s state
+	s.initialize(256)
+
+	var counter [8]byte
+	block := make([]byte, 32)
+	for i := 0; i < iterations; i++ {
+		binary.BigEndian.PutUint64(counter[:], uint64(i))
+		s.absorb(password)
+		s.absorbStop()
+		s.absorb(salt)
+		s.absorbStop()
+		s.absorb(counter[:])
+		s.squeeze(block)
+	}
+
+	// absorb the length
+	binary.BigEndian.PutUint64(counter[:], uint64(keyLen))
+	s.absorbStop()
+	s.absorb(counter[:])
+
+	key := make([]byte, keyLen)
+	s.squeeze(key)
+	return key
+}
@@ -1,11 +1,49 @@
 package spritz_test
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/codahale/spritz"
 )
 
+func TestDeriveKey(t *testing.T) {
+	password, salt := []byte("password"), []byte("salt")
+	key := spritz.DeriveKey(password, salt, 10, 32)
+
+	if len(key) != 32 {
+		t.Errorf("Key was %d bytes but expected 32", len(key))
+	}
+
+	if k := spritz.DeriveKey(password, salt, 10, 32); !bytes.Equal(k, key) {
+		t.Errorf("Output for same inputs was \n%x\n but expected\n%x", k, key)
+	}
+
+	fixtures := []struct {
+		name     string
+		password []byte
+		salt     []byte
+		iter     int
+	}{
+		{"password", []byte("Password"), salt, 10},
+		{"salt", password, []byte("pepper"), 10},
+		{"iterations", password, salt, 11},
+	}
+
+	for _, f := range fixtures {
+		k := spritz.DeriveKey(f.password, f.salt, f.iter, 32)
+		if bytes.Equal(k, key) {
+			t.Errorf("Changing the %s didn't change the key: %x", f.name, k)
+		}
+	}
+
+	for _, n := range []int{0, 1, 16, 300} {
+		if k := spritz.DeriveKey(password, salt, 10, n); len(k) != n {
+			t.Errorf("Key was %d bytes but expected %d", len(k), n)
+		}
+	}
+}
+
 func BenchmarkPasswordHash(b *testing.B) {
 	v := []byte("hello this is a password")
 	b.ReportAllocs()
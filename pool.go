@@ -0,0 +1,26 @@
+package spritz
+
+import "sync"
+
+var streams = sync.Pool{
+	New: func() interface{} {
+		return new(Stream)
+	},
+}
+
+// GetStream returns an instance of the Spritz cipher using the given key, like
+// NewStream, but reuses the memory of a cipher previously returned with
+// PutStream if one is available. It is safe for concurrent use.
+func GetStream(key []byte) *Stream {
+	s := streams.Get().(*Stream)
+	s.n = 256
+	s.Rekey(key)
+	return s
+}
+
+// PutStream wipes the given cipher and makes its memory available for reuse by
+// GetStream. The cipher must not be used after it has been returned.
+func PutStream(s *Stream) {
+	s.Wipe()
+	streams.Put(s)
+}
@@ -0,0 +1,78 @@
+package spritz_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/codahale/spritz"
+)
+
+func TestGetStream(t *testing.T) {
+	for _, key := range []string{"ABC", "spam", "arcfour"} {
+		s := spritz.GetStream([]byte(key))
+
+		out := make([]byte, 32)
+		s.XORKeyStream(out, out)
+		spritz.PutStream(s)
+
+		expected := make([]byte, 32)
+		spritz.NewStream([]byte(key)).XORKeyStream(expected, expected)
+
+		if !bytes.Equal(out, expected) {
+			t.Errorf("Output for %q was \n%x\n but expected\n%x", key, out, expected)
+		}
+	}
+}
+
+func TestGetStreamResetsOptions(t *testing.T) {
+	key := []byte("arcfour")
+	expected := make([]byte, 32)
+	spritz.NewStream(key).XORKeyStream(expected, expected)
+
+	for _, s := range []*spritz.Stream{
+		spritz.NewStreamWithIV(key, []byte{}),
+		spritz.NewStreamSalted(key, []byte("salt")),
+		spritz.NewStreamDrop(key, 100),
+		spritz.NewStreamNonceCounter(key, make([]byte, 12), 7),
+		spritz.NewStreamN(key, 512),
+	} {
+		spritz.PutStream(s)
+
+		g := spritz.GetStream(key)
+		out := make([]byte, 32)
+		g.XORKeyStream(out, out)
+		spritz.PutStream(g)
+
+		if !bytes.Equal(out, expected) {
+			t.Errorf("Output was \n%x\n but expected\n%x", out, expected)
+		}
+	}
+}
+
+func BenchmarkNewStreamParallel(b *testing.B) {
+	key := []byte{'a', 'r', 'c', 'f', 'o', 'u', 'r'}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		out := make([]byte, 64)
+		for pb.Next() {
+			spritz.NewStream(key).XORKeyStream(out, out)
+		}
+	})
+}
+
+func BenchmarkGetStreamParallel(b *testing.B) {
+	key := []byte{'a', 'r', 'c', 'f', 'o', 'u', 'r'}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		out := make([]byte, 64)
+		for pb.Next() {
+			s := spritz.GetStream(key)
+			s.XORKeyStream(out, out)
+			spritz.PutStream(s)
+		}
+	})
+}
@@ -0,0 +1,165 @@
+package spritz
+
+import (
+	"encoding/binary"
+	"io"
+	"math/rand"
+)
+
+var _ rand.Source64 = &Source{}
+
+// NewSource returns a new deterministic source of pseudo-random values which is
+// seeded with the given key. A source produces the same sequence of values for
+// the same seed, which makes it suitable for reproducible simulations and
+// tests. It is not safe for concurrent use.
+func NewSource(seed []byte) *Source {
+	var s Source
+	s.seed(seed)
+	return &s
+}
+
+// NewRandReader returns a deterministic source of random bytes seeded with the
+// given key, for use wherever a crypto/rand.Reader-style io.Reader is needed in
+// reproducible tests or simulations. Readers with the same key produce the same
+// bytes. It is NOT a substitute for crypto/rand.Reader when the output must be
+// unpredictable: anyone who knows the key can reproduce every byte.
+func NewRandReader(key []byte) io.Reader {
+	return NewReader(key)
+}
+
+// Perm returns a pseudo-random permutation of the integers [0, n), determined
+// entirely by the given key, for reproducible shuffling and sampling. It starts
+// from the identity permutation and applies a Fisher-Yates shuffle driven by
+// NewSource(key): for each i from n-1 down to 1, it swaps element i with
+// element j, where j is the first value returned by Uint64 below the largest
+// multiple of i+1 not exceeding 2**64, reduced modulo i+1. Rejecting the values
+// above that multiple keeps every permutation equally likely. It panics if n is
+// negative.
+func Perm(key []byte, n int) []int {
+	if n < 0 {
+		panic("spritz: negative permutation size")
+	}
+
+	p := make([]int, n)
+	for i := range p {
+		p[i] = i
+	}
+
+	s := NewSource(key)
+	for i := n - 1; i > 0; i-- {
+		j := s.uniform(uint64(i) + 1)
+		p[i], p[j] = p[j], p[i]
+	}
+	return p
+}
+
+// TokenGenerator returns a function which yields successive tokenLen-byte
+// tokens drawn from a single state keyed with key, so that deriving many tokens
+// costs one key setup rather than one per token. Each token is the next
+// tokenLen bytes of the keystream, so tokens never overlap, and the sequence is
+// the same for the same key. The returned function advances shared state, so it
+// is not safe for concurrent use without external locking. It panics if
+// tokenLen is not positive.
+func TokenGenerator(key []byte, tokenLen int) func() []byte {
+	if tokenLen <= 0 {
+		panic("spritz: non-positive token length")
+	}
+
+	var s state
+	s.initialize(256)
+	s.keySetup(key)
+	return func() []byte {
+		token := make([]byte, tokenLen)
+		s.squeeze(token)
+		return token
+	}
+}
+
+// Source is a math/rand.Source64 backed by the Spritz keystream. The zero value
+// is ready to use, and produces the same values as NewSource(nil).
+type Source struct {
+	s state
+}
+
+// keyed returns the source's state, seeding it with an empty key first if the
+// source is a zero value.
+func (s *Source) keyed() *state {
+	if s.s.n == 0 {
+		s.seed(nil)
+	}
+	return &s.s
+}
+
+// Uint64 returns a pseudo-random 64-bit value assembled from the next eight
+// keystream bytes in little-endian order.
+func (s *Source) Uint64() uint64 {
+	return s.keyed().dripUint64()
+}
+
+// Uint32 returns a pseudo-random 32-bit value assembled from the next four
+// keystream bytes in little-endian order.
+func (s *Source) Uint32() uint32 {
+	st := s.keyed()
+	var b [4]byte
+	for i := range b {
+		b[i] = byte(st.drip())
+	}
+	return binary.LittleEndian.Uint32(b[:])
+}
+
+// Read fills p with the next len(p) keystream bytes. It always returns len(p)
+// and a nil error.
+func (s *Source) Read(p []byte) (int, error) {
+	st := s.keyed()
+	for i := range p {
+		p[i] = byte(st.drip())
+	}
+	return len(p), nil
+}
+
+// Discard advances the source past the next n values which Uint64 would have
+// returned, without computing them.
+func (s *Source) Discard(n int) {
+	st := s.keyed()
+	for i := 0; i < 8*n; i++ {
+		st.drip()
+	}
+}
+
+// Int63 returns a non-negative pseudo-random 63-bit integer.
+func (s *Source) Int63() int64 {
+	return int64(s.Uint64() & (1<<63 - 1))
+}
+
+// Seed re-keys the source with the little-endian encoding of the given seed.
+func (s *Source) Seed(seed int64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(seed))
+	s.seed(b[:])
+}
+
+// dripUint64 returns the next eight output values as the bytes of a
+// little-endian 64-bit value.
+func (s *state) dripUint64() uint64 {
+	var b [8]byte
+	for i := range b {
+		b[i] = byte(s.drip())
+	}
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+func (s *Source) seed(key []byte) {
+	s.s.initialize(256)
+	s.s.keySetup(key)
+}
+
+// uniform returns a uniformly distributed value in [0, m), rejecting values of
+// Uint64 which would bias the result.
+func (s *Source) uniform(m uint64) uint64 {
+	limit := -(-m % m) // the largest multiple of m, modulo 2**64
+	for {
+		if v := s.Uint64(); limit == 0 || v < limit {
+			return v % m
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package spritz_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/codahale/spritz"
+)
+
+func TestSource(t *testing.T) {
+	a := spritz.NewSource([]byte("arcfour"))
+	b := spritz.NewSource([]byte("arcfour"))
+	c := spritz.NewSource([]byte("spam"))
+
+	diverged := false
+	for i := 0; i < 100; i++ {
+		x, y, z := a.Uint64(), b.Uint64(), c.Uint64()
+		if x != y {
+			t.Fatalf("Value %d was %x but expected %x", i, y, x)
+		}
+		if x != z {
+			diverged = true
+		}
+	}
+
+	if !diverged {
+		t.Error("Different seeds produced the same sequence")
+	}
+}
+
+func TestSourceInt63(t *testing.T) {
+	s := spritz.NewSource([]byte("arcfour"))
+	for i := 0; i < 1000; i++ {
+		if v := s.Int63(); v < 0 {
+			t.Fatalf("Int63 returned negative value %d", v)
+		}
+	}
+}
+
+func TestSourceSeed(t *testing.T) {
+	a := rand.New(spritz.NewSource(nil))
+	a.Seed(42)
+
+	b := rand.New(spritz.NewSource([]byte("ignored")))
+	b.Seed(42)
+
+	for i := 0; i < 100; i++ {
+		if x, y := a.Int(), b.Int(); x != y {
+			t.Fatalf("Value %d was %d but expected %d", i, y, x)
+		}
+	}
+}
+
+func TestRandReader(t *testing.T) {
+	a := make([]byte, 1000)
+	if _, err := io.ReadFull(spritz.NewRandReader([]byte("arcfour")), a); err != nil {
+		t.Fatal(err)
+	}
+
+	b := make([]byte, 1000)
+	if _, err := io.ReadFull(spritz.NewRandReader([]byte("arcfour")), b); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(a, b) {
+		t.Errorf("Output was \n%x\n but expected\n%x", b, a)
+	}
+}
+
+func TestSourceDiscard(t *testing.T) {
+	for _, k := range []int{0, 1, 10} {
+		a := spritz.NewSource([]byte("arcfour"))
+		a.Discard(k)
+
+		b := spritz.NewSource([]byte("arcfour"))
+		for i := 0; i < k; i++ {
+			b.Uint64()
+		}
+
+		if x, y := a.Uint64(), b.Uint64(); x != y {
+			t.Errorf("Value after Discard(%d) was %x but expected %x", k, x, y)
+		}
+	}
+}
+
+func TestSourceUint32(t *testing.T) {
+	key := []byte("arcfour")
+	expected := spritz.Keystream(key, 16)
+
+	s := spritz.NewSource(key)
+	for i := 0; i < 4; i++ {
+		if v, e := s.Uint32(), binary.LittleEndian.Uint32(expected[4*i:]); v != e {
+			t.Errorf("Value %d was %x but expected %x", i, v, e)
+		}
+	}
+}
+
+func TestSourceRead(t *testing.T) {
+	key := []byte("arcfour")
+	expected := spritz.Keystream(key, 16)
+
+	s := spritz.NewSource(key)
+	out := make([]byte, 12)
+	if _, err := s.Read(out); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(out, expected[:12]) {
+		t.Errorf("Output was \n%x\n but expected\n%x", out, expected[:12])
+	}
+
+	if v, e := s.Uint32(), binary.LittleEndian.Uint32(expected[12:]); v != e {
+		t.Errorf("Value after Read was %x but expected %x", v, e)
+	}
+}
+
+func TestPerm(t *testing.T) {
+	p := spritz.Perm([]byte("arcfour"), 100)
+
+	seen := make([]bool, len(p))
+	for _, v := range p {
+		if v < 0 || v >= len(p) || seen[v] {
+			t.Fatalf("Perm returned an invalid permutation: %v", p)
+		}
+		seen[v] = true
+	}
+
+	if q := spritz.Perm([]byte("arcfour"), 100); !reflect.DeepEqual(p, q) {
+		t.Errorf("The same key produced different permutations:\n%v\n%v", p, q)
+	}
+
+	if q := spritz.Perm([]byte("spam"), 100); reflect.DeepEqual(p, q) {
+		t.Errorf("Different keys produced the same permutation: %v", p)
+	}
+
+	if p := spritz.Perm([]byte("arcfour"), 0); len(p) != 0 {
+		t.Errorf("Perm of zero elements was %v", p)
+	}
+}
+
+func TestPermDistribution(t *testing.T) {
+	counts := make(map[[3]int]int)
+	for i := 0; i < 6000; i++ {
+		var key [8]byte
+		binary.LittleEndian.PutUint64(key[:], uint64(i))
+
+		var p [3]int
+		copy(p[:], spritz.Perm(key[:], 3))
+		counts[p]++
+	}
+
+	if len(counts) != 6 {
+		t.Fatalf("Produced %d distinct permutations of 3 elements", len(counts))
+	}
+	for p, n := range counts {
+		if n < 850 || n > 1150 {
+			t.Errorf("Permutation %v occurred %d times in 6000", p, n)
+		}
+	}
+}
+
+func TestTokenGenerator(t *testing.T) {
+	key := []byte("arcfour")
+	next := spritz.TokenGenerator(key, 16)
+
+	keystream := spritz.Keystream(key, 5*16)
+	for i := 0; i < 5; i++ {
+		token, expected := next(), keystream[16*i:16*(i+1)]
+		if !bytes.Equal(token, expected) {
+			t.Errorf("Token %d was \n%x\n but expected\n%x", i, token, expected)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Created a generator of empty tokens")
+		}
+	}()
+	spritz.TokenGenerator(key, 0)
+}
+
+func TestSourceZeroValue(t *testing.T) {
+	var zero spritz.Source
+	expected := spritz.NewSource(nil)
+
+	for i := 0; i < 10; i++ {
+		if v, e := zero.Uint64(), expected.Uint64(); v != e {
+			t.Errorf("Value %d was %x but expected %x", i, v, e)
+		}
+	}
+
+	for _, f := range []func(*spritz.Source){
+		func(s *spritz.Source) { s.Uint32() },
+		func(s *spritz.Source) { _, _ = s.Read(make([]byte, 3)) },
+		func(s *spritz.Source) { s.Discard(2) },
+		func(s *spritz.Source) { s.Int63() },
+	} {
+		var s spritz.Source
+		seeded := spritz.NewSource(nil)
+		f(&s)
+		f(seeded)
+		if v, e := s.Uint64(), seeded.Uint64(); v != e {
+			t.Errorf("Value was %x but expected %x", v, e)
+		}
+	}
+}
+
+func BenchmarkSource(b *testing.B) {
+	s := spritz.NewSource([]byte{'a', 'r', 'c', 'f', 'o', 'u', 'r'})
+	b.SetBytes(8)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		s.Uint64()
+	}
+}
@@ -0,0 +1,54 @@
+package spritz
+
+import "io"
+
+// NewReader returns an io.Reader which produces the raw keystream of the Spritz
+// cipher using the given key. Reads never block and always fill the given
+// buffer.
+func NewReader(key []byte) io.Reader {
+	var s state
+	s.initialize(256)
+	s.keySetup(key)
+	return reader{s: &s}
+}
+
+var (
+	_ io.WriterTo   = reader{}
+	_ io.ByteReader = reader{}
+)
+
+type reader struct {
+	s *state
+}
+
+func (r reader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(r.s.drip())
+	}
+	return len(p), nil
+}
+
+// ReadByte returns the next keystream byte. It never returns an error.
+func (r reader) ReadByte() (byte, error) {
+	return byte(r.s.drip()), nil
+}
+
+// WriteTo writes keystream to w until w returns an error, and returns the
+// number of bytes written along with that error. The keystream is infinite, so
+// WriteTo only returns once w fails; use io.CopyN or a writer which stops
+// accepting data to bound the amount of keystream produced.
+func (r reader) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	var buf [4096]byte
+	for {
+		_, _ = r.Read(buf[:])
+		n, err := w.Write(buf[:])
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+		if n != len(buf) {
+			return total, io.ErrShortWrite
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package spritz_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/codahale/spritz"
+)
+
+func TestReader(t *testing.T) {
+	key := []byte("arcfour")
+	r := spritz.NewReader(key)
+
+	out := make([]byte, 64)
+	if n, err := r.Read(out); n != len(out) || err != nil {
+		t.Fatalf("Read returned %d, %v", n, err)
+	}
+
+	expected := make([]byte, 64)
+	spritz.NewStream(key).XORKeyStream(expected, expected)
+
+	if !bytes.Equal(out, expected) {
+		t.Errorf("Output for %q was \n%x\n but expected\n%x", key, out, expected)
+	}
+}
+
+func TestReaderCopyN(t *testing.T) {
+	key := []byte("arcfour")
+
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, spritz.NewReader(key), 1000); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := make([]byte, 1000)
+	spritz.NewStream(key).XORKeyStream(expected, expected)
+
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Errorf("Output for %q was \n%x\n but expected\n%x", key, buf.Bytes(), expected)
+	}
+}
+
+type limitedWriter struct {
+	buf bytes.Buffer
+	n   int
+}
+
+var errFull = errors.New("writer full")
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if w.buf.Len()+len(p) > w.n {
+		p = p[:w.n-w.buf.Len()]
+		_, _ = w.buf.Write(p)
+		return len(p), errFull
+	}
+	return w.buf.Write(p)
+}
+
+func TestReaderWriteTo(t *testing.T) {
+	key := []byte("arcfour")
+
+	w := &limitedWriter{n: 10000}
+	n, err := spritz.NewReader(key).(io.WriterTo).WriteTo(w)
+	if n != 10000 || err != errFull {
+		t.Fatalf("WriteTo returned %d, %v", n, err)
+	}
+
+	if expected := spritz.Keystream(key, 10000); !bytes.Equal(w.buf.Bytes(), expected) {
+		t.Errorf("Output for %q was \n%x\n but expected\n%x", key, w.buf.Bytes(), expected)
+	}
+}
+
+func TestReaderReadByte(t *testing.T) {
+	key := []byte("arcfour")
+	r := spritz.NewReader(key).(io.ByteReader)
+
+	out := make([]byte, 64)
+	for i := 0; i < 10; i++ {
+		c, err := r.ReadByte()
+		if err != nil {
+			t.Fatal(err)
+		}
+		out[i] = c
+	}
+	_, _ = r.(io.Reader).Read(out[10:])
+
+	if expected := spritz.Keystream(key, 64); !bytes.Equal(out, expected) {
+		t.Errorf("Output for %q was \n%x\n but expected\n%x", key, out, expected)
+	}
+}
+
+func BenchmarkReader(b *testing.B) {
+	r := spritz.NewReader([]byte{'a', 'r', 'c', 'f', 'o', 'u', 'r'})
+	out := make([]byte, 1024)
+	b.SetBytes(int64(len(out)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = r.Read(out)
+	}
+}
@@ -0,0 +1,46 @@
+package spritz
+
+import "errors"
+
+var errSelfTest = errors.New("spritz: self-test failed")
+
+// selfTestVectors are the test vectors from the Spritz paper, which gives only
+// the first 8 bytes of each output.
+var selfTestVectors = []struct {
+	input           string
+	keystream, hash []byte
+}{
+	{
+		"ABC",
+		[]byte{0x77, 0x9a, 0x8e, 0x01, 0xf9, 0xe9, 0xcb, 0xc0},
+		[]byte{0x02, 0x8f, 0xa2, 0xb4, 0x8b, 0x93, 0x4a, 0x18},
+	},
+	{
+		"spam",
+		[]byte{0xf0, 0x60, 0x9a, 0x1d, 0xf1, 0x43, 0xce, 0xbf},
+		[]byte{0xac, 0xbb, 0xa0, 0x81, 0x3f, 0x30, 0x0d, 0x3a},
+	},
+	{
+		"arcfour",
+		[]byte{0x1a, 0xfa, 0x8b, 0x5e, 0xe3, 0x37, 0xdb, 0xc7},
+		[]byte{0xff, 0x8c, 0xf2, 0x68, 0x09, 0x4c, 0x87, 0xb9},
+	},
+}
+
+// SelfTest checks the cipher and the hash against the reference test vectors
+// from the Spritz paper, and returns an error if any output does not match. It
+// is meant to be run at startup, in the manner of a power-on self-test, to
+// catch miscompilation or porting errors.
+func SelfTest() error {
+	for _, v := range selfTestVectors {
+		if !Equal(Keystream([]byte(v.input), len(v.keystream)), v.keystream) {
+			return errSelfTest
+		}
+
+		h := Sum256([]byte(v.input))
+		if !Equal(h[:len(v.hash)], v.hash) {
+			return errSelfTest
+		}
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package spritz_test
+
+import (
+	"testing"
+
+	"github.com/codahale/spritz"
+)
+
+func TestSelfTest(t *testing.T) {
+	if err := spritz.SelfTest(); err != nil {
+		t.Error(err)
+	}
+}
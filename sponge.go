@@ -0,0 +1,180 @@
+package spritz
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"math/bits"
+)
+
+// NewSponge returns a new Spritz sponge with a state size of N, which must be
+// at least 16. N is usually 256.
+func NewSponge(n int) *Sponge {
+	checkN(n)
+	var s Sponge
+	s.s.initialize(n)
+	return &s
+}
+
+// Sponge is the Spritz sponge function, which can be used to build custom
+// constructions on top of the Spritz permutation. It is not safe for
+// concurrent use.
+//
+// Every method which squeezes bytes encodes output values the same way. For
+// N=256 each byte is one output value. For other state sizes, whose output
+// values do not fit a byte exactly, the bytes are packed from the output values
+// as described by NewStreamN, so they are uniform and use every bit of each
+// value, and a sponge which has only absorbed a key produces the keystream of
+// NewStreamN for that key. Bits left over at the end of one squeeze are used by
+// the next, so squeezing in pieces produces the same bytes as squeezing at
+// once, until they are discarded by absorbing or by Drip or SqueezeInts.
+type Sponge struct {
+	s state
+
+	// for N other than 256, output values are packed into bytes
+	acc  uint64 // buffered output bits
+	nacc uint   // number of buffered bits
+}
+
+// Absorb absorbs p into the sponge.
+func (s *Sponge) Absorb(p []byte) {
+	s.discard()
+	s.s.absorb(p)
+}
+
+// AbsorbFrom absorbs everything read from r until EOF, through a fixed-size
+// buffer, and returns the number of bytes absorbed. The result is the same as
+// calling Absorb with everything read. Any error other than EOF is returned,
+// after absorbing the bytes read before it.
+func (s *Sponge) AbsorbFrom(r io.Reader) (int64, error) {
+	s.discard()
+	return s.s.absorbFrom(r)
+}
+
+// AbsorbStop absorbs a special stop symbol, which separates inputs so that,
+// for example, absorbing "ab" and "c" produces a different state than absorbing
+// "a" and "bc". A stop consumes one absorb slot, as a nibble of input does, so
+// like any absorbed input it may first trigger a shuffle of the state.
+func (s *Sponge) AbsorbStop() {
+	s.discard()
+	s.s.absorbStop()
+}
+
+// Squeeze fills out with output from the sponge, encoded as described for
+// Sponge.
+func (s *Sponge) Squeeze(out []byte) {
+	if s.s.n == 256 {
+		s.s.squeeze(out)
+		return
+	}
+
+	width := uint(bits.Len(uint(s.s.n)) - 1)
+	for i := range out {
+		out[i] = packedByte(&s.s, width, &s.acc, &s.nacc)
+	}
+}
+
+// discard drops any output bits buffered by Squeeze.
+func (s *Sponge) discard() {
+	s.acc, s.nacc = 0, 0
+}
+
+// Drip returns the next Spritz output value, in the range 0 to N-1, shuffling
+// the state first if anything has been absorbed since the last output. For
+// N=256 it is always a valid byte, and is the byte Squeeze would have
+// produced.
+func (s *Sponge) Drip() int {
+	s.discard()
+	return s.s.drip()
+}
+
+// SqueezeInts fills out with whole output values from the sponge, each in the
+// range 0 to N-1, which for N>256 may exceed 255. Unlike Squeeze, which packs
+// only the bits of each value below the largest power of two not exceeding N,
+// it loses nothing, so it is the way to consume the full output of a wide
+// sponge. It is equivalent to calling Drip len(out) times.
+func (s *Sponge) SqueezeInts(out []int) {
+	s.discard()
+	if s.s.a > 0 {
+		s.s.shuffle()
+	}
+	for i := range out {
+		s.s.update()
+		out[i] = s.s.output()
+	}
+}
+
+// SqueezeBytes squeezes and returns n bytes of output from the sponge, as
+// Squeeze would write them into a new slice.
+func (s *Sponge) SqueezeBytes(n int) []byte {
+	out := make([]byte, n)
+	s.Squeeze(out)
+	return out
+}
+
+// SqueezeUint64 squeezes eight bytes from the sponge and returns them as a
+// 64-bit value in little-endian order, so the first byte becomes the least
+// significant. It matches what Squeeze would produce for the same eight bytes,
+// and for N=256 is the primitive behind Source.Uint64.
+func (s *Sponge) SqueezeUint64() uint64 {
+	if s.s.n == 256 {
+		return s.s.dripUint64()
+	}
+
+	var b [8]byte
+	s.Squeeze(b[:])
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+// Duplex absorbs in, absorbs a stop, and then squeezes and returns len(in)
+// bytes of output. The sponge's state carries over from each call to the next,
+// so every output depends on all inputs absorbed and all outputs squeezed
+// before it, in order. A call to Duplex is exactly equivalent to calling
+// Absorb(in), AbsorbStop, and Squeeze with a buffer of len(in) bytes.
+func (s *Sponge) Duplex(in []byte) []byte {
+	s.Absorb(in)
+	s.AbsorbStop()
+	out := make([]byte, len(in))
+	s.Squeeze(out)
+	return out
+}
+
+// SqueezeTo squeezes n bytes of output from the sponge and writes them to w,
+// through a fixed-size buffer, so that large outputs need not be held in
+// memory. It returns the number of bytes written, stopping at the first error
+// returned by w. Like Squeeze, it advances the sponge's state, so calling it
+// twice produces different output.
+func (s *Sponge) SqueezeTo(w io.Writer, n int) (int, error) {
+	return s.SqueezeToContext(context.Background(), w, n)
+}
+
+// SqueezeToContext squeezes n bytes of output from the sponge and writes them
+// to w, like SqueezeTo, but stops early with ctx's error if ctx is done. The
+// context is checked before each 4 KiB chunk is squeezed, so cancellation takes
+// effect within one chunk and one write. It returns the number of bytes written
+// before it stopped.
+func (s *Sponge) SqueezeToContext(ctx context.Context, w io.Writer, n int) (int, error) {
+	var buf [4096]byte
+	written := 0
+	for written < n {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+
+		chunk := buf[:]
+		if n-written < len(chunk) {
+			chunk = chunk[:n-written]
+		}
+		s.Squeeze(chunk)
+
+		m, err := w.Write(chunk)
+		written += m
+		if err != nil {
+			return written, err
+		}
+		if m != len(chunk) {
+			return written, io.ErrShortWrite
+		}
+	}
+	return written, nil
+}
@@ -0,0 +1,400 @@
+package spritz_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/codahale/spritz"
+)
+
+func TestSponge(t *testing.T) {
+	for _, key := range []string{"ABC", "spam", "arcfour"} {
+		s := spritz.NewSponge(256)
+		s.Absorb([]byte(key))
+
+		out := make([]byte, 32)
+		s.Squeeze(out)
+
+		if expected := spritz.Keystream([]byte(key), 32); !bytes.Equal(out, expected) {
+			t.Errorf("Output for %q was \n%x\n but expected\n%x", key, out, expected)
+		}
+	}
+}
+
+func TestSpongeAbsorbStop(t *testing.T) {
+	a := spritz.NewSponge(256)
+	a.Absorb([]byte("ab"))
+	a.AbsorbStop()
+	a.Absorb([]byte("c"))
+
+	b := spritz.NewSponge(256)
+	b.Absorb([]byte("a"))
+	b.AbsorbStop()
+	b.Absorb([]byte("bc"))
+
+	outA := make([]byte, 32)
+	a.Squeeze(outA)
+
+	outB := make([]byte, 32)
+	b.Squeeze(outB)
+
+	if bytes.Equal(outA, outB) {
+		t.Errorf("Different field boundaries produced the same output: %x", outA)
+	}
+}
+
+func TestSpongeAbsorbStopShuffles(t *testing.T) {
+	// the 129th stop runs past the 128 absorb slots and forces a shuffle, but
+	// must still be distinguishable from stopping earlier
+	a := spritz.NewSponge(256)
+	b := spritz.NewSponge(256)
+	for i := 0; i < 127; i++ {
+		a.AbsorbStop()
+		b.AbsorbStop()
+	}
+	a.AbsorbStop()
+	a.AbsorbStop()
+
+	outA, outB := make([]byte, 32), make([]byte, 32)
+	a.Squeeze(outA)
+	b.Squeeze(outB)
+
+	if bytes.Equal(outA, outB) {
+		t.Errorf("Stops across a shuffle produced the same output: %x", outA)
+	}
+}
+
+func TestSpongeTooSmall(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Created a sponge with N=8")
+		}
+	}()
+
+	spritz.NewSponge(8)
+}
+
+func TestSpongeDuplex(t *testing.T) {
+	a := spritz.NewSponge(256)
+	b := spritz.NewSponge(256)
+
+	for _, in := range []string{"one", "", "three"} {
+		out := a.Duplex([]byte(in))
+
+		b.Absorb([]byte(in))
+		b.AbsorbStop()
+		expected := make([]byte, len(in))
+		b.Squeeze(expected)
+
+		if !bytes.Equal(out, expected) {
+			t.Errorf("Output for %q was \n%x\n but expected\n%x", in, out, expected)
+		}
+	}
+}
+
+func TestSpongeSqueezeTo(t *testing.T) {
+	a := spritz.NewSponge(256)
+	a.Absorb([]byte("arcfour"))
+
+	var buf bytes.Buffer
+	if n, err := a.SqueezeTo(&buf, 10000); n != 10000 || err != nil {
+		t.Fatalf("SqueezeTo returned %d, %v", n, err)
+	}
+
+	b := spritz.NewSponge(256)
+	b.Absorb([]byte("arcfour"))
+	expected := make([]byte, 10000)
+	b.Squeeze(expected)
+
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Errorf("Output was \n%x\n but expected\n%x", buf.Bytes(), expected)
+	}
+
+	if n, err := a.SqueezeTo(errWriter{}, 100); n != 0 || err != errWrite {
+		t.Errorf("SqueezeTo to a failing writer returned %d, %v", n, err)
+	}
+}
+
+// cancelWriter cancels its context after its first write.
+type cancelWriter struct {
+	bytes.Buffer
+	cancel context.CancelFunc
+}
+
+func (w *cancelWriter) Write(p []byte) (int, error) {
+	w.cancel()
+	return w.Buffer.Write(p)
+}
+
+func TestSpongeSqueezeToContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := spritz.NewSponge(256)
+	a.Absorb([]byte("arcfour"))
+
+	w := &cancelWriter{cancel: cancel}
+	n, err := a.SqueezeToContext(ctx, w, 10000)
+	if n != 4096 || err != context.Canceled {
+		t.Fatalf("SqueezeToContext returned %d, %v", n, err)
+	}
+
+	b := spritz.NewSponge(256)
+	b.Absorb([]byte("arcfour"))
+	expected := make([]byte, 4096)
+	b.Squeeze(expected)
+
+	if !bytes.Equal(w.Bytes(), expected) {
+		t.Errorf("Output was \n%x\n but expected\n%x", w.Bytes(), expected)
+	}
+
+	if n, err := a.SqueezeToContext(ctx, w, 100); n != 0 || err != context.Canceled {
+		t.Errorf("SqueezeToContext with a canceled context returned %d, %v", n, err)
+	}
+}
+
+func TestSpongeDrip(t *testing.T) {
+	a := spritz.NewSponge(256)
+	a.Absorb([]byte("arcfour"))
+
+	out := make([]byte, 32)
+	for i := range out {
+		out[i] = byte(a.Drip())
+	}
+
+	if expected := spritz.Keystream([]byte("arcfour"), 32); !bytes.Equal(out, expected) {
+		t.Errorf("Output was \n%x\n but expected\n%x", out, expected)
+	}
+
+	b := spritz.NewSponge(17)
+	b.Absorb([]byte("arcfour"))
+	for i := 0; i < 1000; i++ {
+		if v := b.Drip(); v < 0 || v >= 17 {
+			t.Fatalf("Drip returned %d for N=17", v)
+		}
+	}
+}
+
+func TestSpongeSqueezeInts(t *testing.T) {
+	for _, n := range []int{256, 1024} {
+		a := spritz.NewSponge(n)
+		a.Absorb([]byte("arcfour"))
+		out := make([]int, 1000)
+		a.SqueezeInts(out)
+
+		b := spritz.NewSponge(n)
+		b.Absorb([]byte("arcfour"))
+		wide := false
+		for i, v := range out {
+			if expected := b.Drip(); v != expected {
+				t.Fatalf("N=%d: Value %d was %d but expected %d", n, i, v, expected)
+			}
+			wide = wide || v > 255
+		}
+
+		if wide != (n > 256) {
+			t.Errorf("N=%d: Values above 255 were output: %v", n, wide)
+		}
+	}
+}
+
+func TestSpongeAbsorbFrom(t *testing.T) {
+	in := bytes.Repeat([]byte("arcfour"), 1000)
+
+	a := spritz.NewSponge(256)
+	if n, err := a.AbsorbFrom(bytes.NewReader(in)); n != int64(len(in)) || err != nil {
+		t.Fatalf("AbsorbFrom returned %d, %v", n, err)
+	}
+
+	b := spritz.NewSponge(256)
+	b.Absorb(in)
+
+	outA, outB := make([]byte, 32), make([]byte, 32)
+	a.Squeeze(outA)
+	b.Squeeze(outB)
+
+	if !bytes.Equal(outA, outB) {
+		t.Errorf("Output was \n%x\n but expected\n%x", outA, outB)
+	}
+}
+
+func TestSpongeAbsorbFromError(t *testing.T) {
+	s := spritz.NewSponge(256)
+	if _, err := s.AbsorbFrom(errReader{}); err != errRead {
+		t.Errorf("AbsorbFrom returned %v but expected %v", err, errRead)
+	}
+}
+
+func TestSpongeSqueezeMatchesDrip(t *testing.T) {
+	a := spritz.NewSponge(256)
+	b := spritz.NewSponge(256)
+	a.Absorb([]byte("arcfour"))
+	b.Absorb([]byte("arcfour"))
+
+	out := make([]byte, 10000)
+	a.Squeeze(out[:5000])
+	a.Squeeze(out[5000:])
+
+	for i, v := range out {
+		if d := byte(b.Drip()); d != v {
+			t.Fatalf("Squeezed byte %d was %x but Drip returned %x", i, v, d)
+		}
+	}
+}
+
+func TestSpongeSqueezeUint64(t *testing.T) {
+	a := spritz.NewSponge(256)
+	b := spritz.NewSponge(256)
+	a.Absorb([]byte("arcfour"))
+	b.Absorb([]byte("arcfour"))
+
+	out := make([]byte, 16)
+	b.Squeeze(out)
+
+	for i := 0; i < 2; i++ {
+		if v, expected := a.SqueezeUint64(), binary.LittleEndian.Uint64(out[8*i:]); v != expected {
+			t.Errorf("Value %d was %x but expected %x", i, v, expected)
+		}
+	}
+
+	if v, expected := spritz.NewSource([]byte("arcfour")).Uint64(), binary.LittleEndian.Uint64(out); v != expected {
+		t.Errorf("Source value was %x but expected %x", v, expected)
+	}
+}
+
+func TestSpongeSqueezeBytes(t *testing.T) {
+	for _, n := range []int{256, 17, 300, 1024} {
+		s := spritz.NewSponge(n)
+		s.Absorb([]byte("arcfour"))
+		out := s.SqueezeBytes(64)
+
+		expected := make([]byte, 64)
+		spritz.NewStreamN([]byte("arcfour"), n).XORKeyStream(expected, expected)
+
+		if !bytes.Equal(out, expected) {
+			t.Errorf("Output for N=%d was \n%x\n but expected\n%x", n, out, expected)
+		}
+	}
+}
+
+func TestSpongeAbsorbLengths(t *testing.T) {
+	// outputs of the byte-at-a-time absorb which batched absorbs replaced
+	fixtures := []struct {
+		n        int
+		expected string
+	}{
+		{16, "4448fef5b863bf28a82bd28b7a53a3f679992468814c8d5a75518e13005e0597"},
+		{256, "37d1c2f3f3d81a3430fc75b1a6ee4bc6b623a325b0afa87d8f481701bb8ddec0"},
+		{300, "5ec30256595a290397d5423b3c1188c975b67409d3b6f3f58a260635103e111b"},
+	}
+
+	for _, f := range fixtures {
+		src := spritz.NewSource([]byte("absorb lengths"))
+		whole, split := spritz.NewSponge(f.n), spritz.NewSponge(f.n)
+		for i := 0; i < 200; i++ {
+			msg := make([]byte, src.Uint64()%(3*uint64(f.n)))
+			_, _ = src.Read(msg)
+
+			whole.Absorb(msg)
+			for len(msg) > 0 {
+				l := 1 + int(src.Uint64()%uint64(len(msg)))
+				split.Absorb(msg[:l])
+				msg = msg[l:]
+			}
+
+			if i%3 == 0 {
+				whole.AbsorbStop()
+				split.AbsorbStop()
+			}
+		}
+
+		out := whole.SqueezeBytes(32)
+		if expected, _ := hex.DecodeString(f.expected); !bytes.Equal(out, expected) {
+			t.Errorf("N=%d: Output was \n%x\n but expected\n%x", f.n, out, expected)
+		}
+		if other := split.SqueezeBytes(32); !bytes.Equal(other, out) {
+			t.Errorf("N=%d: Split output was \n%x\n but expected\n%x", f.n, other, out)
+		}
+	}
+}
+
+func TestSpongeSqueezeEncodings(t *testing.T) {
+	key := []byte("arcfour")
+	for _, n := range []int{16, 64, 256} {
+		newSponge := func() *spritz.Sponge {
+			s := spritz.NewSponge(n)
+			s.Absorb(key)
+			return s
+		}
+
+		expected := make([]byte, 5000)
+		spritz.NewStreamN(key, n).XORKeyStream(expected, expected)
+
+		squeezed := make([]byte, len(expected))
+		s := newSponge()
+		s.Squeeze(squeezed[:1])
+		s.Squeeze(squeezed[1:1000])
+		s.Squeeze(squeezed[1000:])
+
+		var buf bytes.Buffer
+		if _, err := newSponge().SqueezeTo(&buf, len(expected)); err != nil {
+			t.Fatal(err)
+		}
+
+		words := make([]byte, len(expected))
+		s = newSponge()
+		for i := 0; i < len(words); i += 8 {
+			binary.LittleEndian.PutUint64(words[i:], s.SqueezeUint64())
+		}
+
+		fixtures := []struct {
+			name string
+			out  []byte
+		}{
+			{"Squeeze", squeezed},
+			{"SqueezeBytes", newSponge().SqueezeBytes(len(expected))},
+			{"SqueezeTo", buf.Bytes()},
+			{"SqueezeUint64", words},
+		}
+
+		for _, f := range fixtures {
+			if !bytes.Equal(f.out, expected) {
+				t.Errorf("N=%d: %s output was \n%x\n but expected\n%x", n, f.name, f.out, expected)
+			}
+		}
+
+		a, b := newSponge(), newSponge()
+		out := a.Duplex([]byte("duplex"))
+		b.Absorb([]byte("duplex"))
+		b.AbsorbStop()
+		if expected := b.SqueezeBytes(len(out)); !bytes.Equal(out, expected) {
+			t.Errorf("N=%d: Duplex output was \n%x\n but expected\n%x", n, out, expected)
+		}
+	}
+}
+
+func BenchmarkSpongeSqueeze(b *testing.B) {
+	s := spritz.NewSponge(256)
+	out := make([]byte, 1024)
+	b.SetBytes(int64(len(out)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		s.Squeeze(out)
+	}
+}
+
+func BenchmarkSpongeSqueezeBytes(b *testing.B) {
+	s := spritz.NewSponge(256)
+	b.SetBytes(1024)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		s.SqueezeBytes(1024)
+	}
+}
@@ -5,41 +5,116 @@
 // http://people.csail.mit.edu/rivest/pubs/RS14.pdf.
 package spritz
 
-import "math"
+import (
+	"crypto/subtle"
+	"io"
+	"math"
+)
+
+// Equal reports whether a and b are equal without leaking timing information
+// about their contents. It should be used whenever a computed Spritz hash or MAC
+// is compared against an expected value. Slices of different lengths are never
+// equal, and their lengths are not considered secret.
+func Equal(a, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
+
+// minN is the smallest supported state size.
+const minN = 16
+
+// checkN panics if n is not a supported state size.
+func checkN(n int) {
+	if n < minN {
+		panic("spritz: state size must be at least 16")
+	}
+}
 
 type state struct {
 	// these are all ints instead of bytes to allow for states > 256
 	n, d             int // state size and nibble size
+	digits           int // nibbles per absorbed byte
 	s                []int
 	a, i, j, k, w, z int
+
+	// when n is a power of two, x % n is computed as x & mask
+	pow2 bool
+	mask int
 }
 
 func (s *state) initialize(n int) {
+	p := s.s
+	if cap(p) < n {
+		p = make([]int, n)
+	}
 	*s = state{
-		s: make([]int, n),
+		s: p[:n], // reuse an existing permutation's memory if possible
 		w: 1,
 		n: n,
 		d: int(math.Ceil(math.Sqrt(float64(n)))),
+
+		pow2: n&(n-1) == 0,
+		mask: n - 1,
 	}
 	for i := range s.s {
 		s.s[i] = i
 	}
+
+	// a byte is absorbed as LOW and HIGH nibbles when d*d covers every byte
+	// value, which is true for all n >= 256; smaller states need more nibbles
+	// to absorb a byte without losing information
+	s.digits = 2
+	for x := s.d * s.d; x < 256; x *= s.d {
+		s.digits++
+	}
+}
+
+func (s *state) clone() *state {
+	var c state
+	s.copyTo(&c)
+	return &c
+}
+
+// copyTo makes c a copy of s, reusing c's permutation memory if possible.
+func (s *state) copyTo(c *state) {
+	p := c.s
+	if cap(p) < len(s.s) {
+		p = make([]int, len(s.s))
+	}
+	*c = *s
+	c.s = p[:len(s.s)]
+	copy(c.s, s.s)
+}
+
+// mod returns x modulo the state size. x must not be negative.
+func (s *state) mod(x int) int {
+	if s.pow2 {
+		return x & s.mask
+	}
+	return x % s.n
+}
+
+// wipe zeroes the state, leaving it unusable until it is re-initialized.
+func (s *state) wipe() {
+	for i := range s.s {
+		s.s[i] = 0
+	}
+	s.a, s.i, s.j, s.k, s.w, s.z = 0, 0, 0, 0, 0, 0
 }
 
 func (s *state) update() {
-	s.i = (s.i + s.w) % s.n
-	y := (s.j + s.s[s.i]) % s.n
-	s.j = (s.k + s.s[y]) % s.n
-	s.k = (s.i + s.k + s.s[s.j]) % s.n
+	s.i = s.mod(s.i + s.w)
+	y := s.mod(s.j + s.s[s.i])
+	s.j = s.mod(s.k + s.s[y])
+	s.k = s.mod(s.i + s.k + s.s[s.j])
 	t := s.s[s.i]
 	s.s[s.i] = s.s[s.j]
 	s.s[s.j] = t
 }
 
 func (s *state) output() int {
-	y1 := (s.z + s.k) % s.n
-	x1 := (s.i + s.s[y1]) % s.n
-	y2 := (s.j + s.s[x1]) % s.n
+	y1 := s.mod(s.z + s.k)
+	x1 := s.mod(s.i + s.s[y1])
+	y2 := s.mod(s.j + s.s[x1])
 	s.z = s.s[y2]
 	return s.z
 }
@@ -64,7 +139,7 @@ func (s *state) whip() {
 	for i := 0; i < r; i++ {
 		s.update()
 	}
-	s.w = (s.w + 2) % s.n
+	s.w = s.mod(s.w + 2)
 }
 
 func (s *state) shuffle() {
@@ -80,28 +155,112 @@ func (s *state) absorbStop() {
 	if s.a == s.n/2 {
 		s.shuffle()
 	}
-	s.a = (s.a + 1) % s.n
+	s.a = s.mod(s.a + 1)
 }
 
 func (s *state) absorbNibble(x int) {
 	if s.a == s.n/2 {
 		s.shuffle()
 	}
-	y := (s.n/2 + x) % s.n
+	y := s.mod(s.n/2 + x)
 	t := s.s[s.a]
 	s.s[s.a] = s.s[y]
 	s.s[y] = t
-	s.a = (s.a + 1) % s.n
+	s.a = s.mod(s.a + 1)
 }
 
 func (s *state) absorbByte(b int) {
-	s.absorbNibble(b % s.d) // LOW
-	s.absorbNibble(b / s.d) // HIGH
+	for i := 1; i < s.digits; i++ {
+		s.absorbNibble(b % s.d) // LOW
+		b /= s.d
+	}
+	s.absorbNibble(b) // HIGH
 }
 
 func (s *state) absorb(msg []byte) {
-	for _, v := range msg {
-		s.absorbByte(int(v))
+	half := s.n / 2
+	for len(msg) > 0 {
+		// every nibble of the next run bytes lands below n/2, so none of them
+		// can trigger a shuffle and they can skip absorbNibble's check
+		run := 0
+		if s.a < half {
+			run = (half - s.a) / s.digits
+		}
+		if run == 0 {
+			s.absorbByte(int(msg[0]))
+			msg = msg[1:]
+			continue
+		}
+		if run > len(msg) {
+			run = len(msg)
+		}
+		s.absorbRun(msg[:run])
+		msg = msg[run:]
+	}
+}
+
+// absorbRun absorbs bytes whose nibbles all land below n/2 without checking
+// for a shuffle. Every nibble is below d, which is at most n/2, so the swapped
+// index never wraps.
+func (s *state) absorbRun(msg []byte) {
+	p, a, half := s.s, s.a, s.n/2
+	if s.digits == 2 && s.d == 16 {
+		for _, v := range msg {
+			y := half + int(v&15) // LOW
+			p[a], p[y] = p[y], p[a]
+			y = half + int(v>>4) // HIGH
+			p[a+1], p[y] = p[y], p[a+1]
+			a += 2
+		}
+	} else {
+		for _, v := range msg {
+			b := int(v)
+			for i := 1; i < s.digits; i++ {
+				y := half + b%s.d // LOW
+				p[a], p[y] = p[y], p[a]
+				b /= s.d
+				a++
+			}
+			y := half + b // HIGH
+			p[a], p[y] = p[y], p[a]
+			a++
+		}
+	}
+	s.a = a
+}
+
+// absorbFrom absorbs everything read from r until EOF through a fixed-size
+// buffer, and returns the number of bytes absorbed. Any error other than EOF is
+// returned, after absorbing the bytes read before it.
+func (s *state) absorbFrom(r io.Reader) (int64, error) {
+	var total int64
+	var buf [4096]byte
+	for {
+		n, err := r.Read(buf[:])
+		s.absorb(buf[:n])
+		total += int64(n)
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// absorbLabel absorbs a stop, label, and another stop, which separates the
+// inputs of one mode of operation from those of another: a label can't be
+// confused with an IV, which is absorbed after a single stop.
+func (s *state) absorbLabel(label string) {
+	s.absorbStop()
+	s.absorb([]byte(label))
+	s.absorbStop()
+}
+
+func (s *state) keySetup(key []byte) {
+	s.absorb(key)
+	if s.a > 0 {
+		s.shuffle()
 	}
 }
 
@@ -117,7 +276,33 @@ func (s *state) squeeze(out []byte) {
 	if s.a > 0 {
 		s.shuffle()
 	}
+	if s.n == 256 {
+		s.squeeze256(out)
+		return
+	}
 	for i := range out {
 		out[i] = byte(s.drip())
 	}
 }
+
+// squeeze256 is the loop of squeeze specialized for N=256, the common case,
+// with update and output inlined and the registers held in locals. Indexing the
+// permutation as a 256-entry array with byte-masked indices lets the compiler
+// drop the bounds checks. It must produce exactly the same output as calling
+// drip for each byte.
+func (s *state) squeeze256(out []byte) {
+	p := (*[256]int)(s.s)
+	i, j, k, w, z := s.i, s.j, s.k, s.w, s.z
+	for n := range out {
+		// update
+		i = (i + w) & 0xff
+		j = (k + p[(j+p[i])&0xff]) & 0xff
+		k = (i + k + p[j]) & 0xff
+		p[i], p[j] = p[j], p[i]
+
+		// output
+		z = p[(j+p[(i+p[(z+k)&0xff])&0xff])&0xff]
+		out[n] = byte(z)
+	}
+	s.i, s.j, s.k, s.z = i, j, k, z
+}
@@ -0,0 +1,44 @@
+package spritz_test
+
+import (
+	"testing"
+
+	"github.com/codahale/spritz"
+)
+
+func TestEqual(t *testing.T) {
+	fixtures := []struct {
+		a, b  []byte
+		equal bool
+	}{
+		{nil, nil, true},
+		{nil, []byte{}, true},
+		{[]byte{1, 2, 3}, []byte{1, 2, 3}, true},
+		{[]byte{1, 2, 3}, []byte{1, 2, 4}, false},
+		{[]byte{1, 2, 3}, []byte{1, 2}, false},
+		{[]byte{1, 2}, []byte{1, 2, 3}, false},
+	}
+
+	for _, f := range fixtures {
+		if v := spritz.Equal(f.a, f.b); v != f.equal {
+			t.Errorf("Equal(%x, %x) was %v but expected %v", f.a, f.b, v, f.equal)
+		}
+	}
+}
+
+func TestAbsorbIsLossless(t *testing.T) {
+	for _, n := range []int{16, 17, 24, 50, 100, 255, 256, 300} {
+		seen := make(map[string]int)
+		for b := 0; b < 256; b++ {
+			h := spritz.NewHashN(16, n)
+			_ = h.WriteByte(byte(b))
+			digest := string(h.Sum(nil))
+
+			if prev, ok := seen[digest]; ok {
+				t.Errorf("N=%d: bytes %d and %d produced the same digest", n, prev, b)
+				break
+			}
+			seen[digest] = b
+		}
+	}
+}
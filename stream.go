@@ -1,36 +1,782 @@
 package spritz
 
-import "crypto/cipher"
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"unsafe"
+)
+
+var _ cipher.Stream = &Stream{}
 
 // NewStream returns a new instance of the Spritz cipher using the given key.
-func NewStream(key []byte) cipher.Stream {
-	return NewStreamWithIV(key, nil)
+// An empty key produces a predictable keystream; use NewStreamChecked to reject
+// empty keys.
+func NewStream(key []byte) *Stream {
+	return NewStreamIV(key, nil)
+}
+
+// NewStreamChecked returns a new instance of the Spritz cipher using the given
+// key, or an error if the key is empty (ErrEmptyKey) or weak (ErrWeakKey). Keys
+// should be at least 16 bytes long.
+func NewStreamChecked(key []byte) (*Stream, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	return NewStream(key), nil
+}
+
+// MaxKeySize is the longest key, in bytes, that NewStreamCanonical absorbs
+// directly.
+const MaxKeySize = 256
+
+// NewStreamCanonical returns a new instance of the Spritz cipher using the
+// given key, like NewStreamChecked, but first replaces keys longer than
+// MaxKeySize bytes with their 32-byte Spritz hash, as computed by Sum256. Keys
+// of MaxKeySize bytes or fewer are used as given, so key setup costs at most
+// MaxKeySize absorbed bytes plus the hash of any longer key. Like
+// NewStreamChecked, it returns an error if the key is empty (ErrEmptyKey) or
+// weak (ErrWeakKey).
+func NewStreamCanonical(key []byte) (*Stream, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	if len(key) > MaxKeySize {
+		h := Sum256(key)
+		key = h[:]
+	}
+	return NewStream(key), nil
 }
 
 // NewStreamWithIV returns a new instance of the Spritz cipher using the given
-// key and initialization vector.
-func NewStreamWithIV(key, iv []byte) cipher.Stream {
+// key and initialization vector. It is equivalent to NewStreamIV, except that a
+// non-nil empty IV still absorbs a stop after the key, so it produces a
+// different keystream than NewStream.
+func NewStreamWithIV(key, iv []byte) *Stream {
+	s := &Stream{
+		key:    append([]byte(nil), key...),
+		iv:     append([]byte(nil), iv...),
+		ivStop: iv != nil,
+		n:      256,
+	}
+	s.Reset()
+	return s
+}
+
+// NewStreamIV returns a new instance of the Spritz cipher using the given key
+// and initialization vector. Distinct IVs produce distinct keystreams, which
+// allows a single key to safely encrypt many messages as long as no IV is ever
+// reused with it. IVs should be at least NonceSize bytes long and either random
+// or unique per message. An empty IV produces the same keystream as NewStream.
+func NewStreamIV(key, iv []byte) *Stream {
+	return newStream(key, iv, 256)
+}
+
+// NewStreamN returns a new instance of the Spritz cipher using the given key
+// and a state size of N, which must be at least 16. Larger states give a larger
+// permutation and a higher security margin, but cost N words of memory and make
+// key setup proportionally slower.
+//
+// For N other than 256, the keystream is built from the Spritz output values
+// as a little-endian bit string: each value below 2**b, where b is the largest
+// integer with 2**b <= N, contributes its b bits, lowest bit first, and values
+// of 2**b or more are discarded to keep the keystream uniform. Keystream bytes
+// are taken from that bit string eight bits at a time, so N values above 256
+// produce more than one keystream byte per output value, and N values below 256
+// do not truncate the output.
+func NewStreamN(key []byte, n int) *Stream {
+	checkN(n)
+	return newStream(key, nil, n)
+}
+
+// NewStreamNChecked returns a new instance of the Spritz cipher using the given
+// key and a state size of N, like NewStreamN, or an error if the key is empty
+// (ErrEmptyKey) or weak (ErrWeakKey), or N is smaller than 16 (ErrInvalidN).
+func NewStreamNChecked(key []byte, n int) (*Stream, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	if n < minN {
+		return nil, ErrInvalidN
+	}
+	return newStream(key, nil, n), nil
+}
+
+// Encrypt returns the encryption of plaintext under the given key and nonce,
+// using the keystream of NewStreamIV. A nonce must never be used to encrypt
+// more than one message with the same key. The ciphertext is not
+// authenticated; use NewAEAD if it may be tampered with.
+func Encrypt(key, nonce, plaintext []byte) []byte {
+	out := make([]byte, len(plaintext))
+	NewStreamIV(key, nonce).XORKeyStream(out, plaintext)
+	return out
+}
+
+// Decrypt returns the decryption of ciphertext under the given key and nonce.
+// Spritz is a stream cipher, so this is the same operation as Encrypt.
+func Decrypt(key, nonce, ciphertext []byte) []byte {
+	return Encrypt(key, nonce, ciphertext)
+}
+
+// NewTransformer returns a function which encrypts each slice passed to it with
+// the next bytes of the keystream of NewStreamIV, and returns the result in a
+// new slice, for composing encryption into pipelines of byte transformations.
+// The keystream continues from each call to the next, so transforming a message
+// in several pieces produces the same output as transforming it at once, and
+// empty slices leave the keystream where it was. Spritz is a stream cipher, so
+// a transformer with the same key and nonce decrypts the output of another,
+// provided it is given the same bytes in the same order. The returned function
+// is not safe for concurrent use.
+func NewTransformer(key, nonce []byte) func([]byte) []byte {
+	s := NewStreamIV(key, nonce)
+	return func(in []byte) []byte {
+		out := make([]byte, len(in))
+		s.XORKeyStream(out, in)
+		return out
+	}
+}
+
+// EncryptBlock returns the encryption of a single fixed-size record under the
+// given key and nonce, for formats made up of independently addressable
+// records. Each record is encrypted with the keystream produced by absorbing a
+// stop, the label "spritz block", another stop, and then the nonce followed by
+// index as an 8-byte big-endian integer into the state after key setup, so
+// every record index gets its own keystream, which no other index or nonce
+// shares with it. The label keeps these keystreams apart from those of Encrypt,
+// NewStreamIV, and NewStreamNonceCounter under the same key. The result is the
+// same length as block. As with Encrypt, a nonce and index must never be used
+// for more than one record with the same key.
+func EncryptBlock(key, nonce []byte, index uint64, block []byte) []byte {
+	iv := make([]byte, len(nonce)+8)
+	copy(iv, nonce)
+	binary.BigEndian.PutUint64(iv[len(nonce):], index)
+
+	var s state
+	s.initialize(256)
+	s.keySetup(key)
+	s.absorbLabel(blockLabel)
+	s.absorb(iv)
+
+	out := make([]byte, len(block))
+	xorKeyStream(&s, out, block)
+	return out
+}
+
+// blockLabel separates the keystreams of EncryptBlock from those of other modes.
+const blockLabel = "spritz block"
+
+// DecryptBlock returns the decryption of a record encrypted by EncryptBlock with
+// the same key, nonce, and index.
+func DecryptBlock(key, nonce []byte, index uint64, block []byte) []byte {
+	return EncryptBlock(key, nonce, index, block)
+}
+
+// NonceForCounter returns a copy of base whose first eight bytes are XORed with
+// counter in little-endian order, for deriving a distinct nonce or IV for each
+// message in a sequence. Distinct counters always produce distinct nonces for
+// the same base, so as long as a counter never repeats for a given key, neither
+// does the nonce. base must be at least eight bytes long, and is usually
+// NonceSize bytes so the result can also be used with NewAEAD.
+func NonceForCounter(base []byte, counter uint64) []byte {
+	if len(base) < 8 {
+		panic("spritz: base nonce must be at least 8 bytes")
+	}
+
+	nonce := append([]byte(nil), base...)
+	for i := 0; i < 8; i++ {
+		nonce[i] ^= byte(counter >> (8 * i))
+	}
+	return nonce
+}
+
+// NewStreamSalted returns a new instance of the Spritz cipher using the given
+// key and salt, for domain-separating a single key across several contexts. The
+// salt is absorbed first, followed by a stop and then the key, and the state is
+// shuffled, so different salts produce unrelated keystreams for the same key.
+// Unlike an IV, which is absorbed after key setup, the salt is part of key
+// setup itself. An empty salt is equivalent to NewStream.
+func NewStreamSalted(key, salt []byte) *Stream {
+	s := &Stream{
+		key:  append([]byte(nil), key...),
+		salt: append([]byte(nil), salt...),
+		n:    256,
+	}
+	s.Reset()
+	return s
+}
+
+// CounterBlockSize is the size, in bytes, of the keystream blocks produced by
+// NewStreamNonceCounter.
+const CounterBlockSize = 1024
+
+// NewStreamNonceCounter returns a new instance of the Spritz cipher using the
+// given key and 12-byte nonce, whose keystream is divided into numbered blocks
+// starting at initialCounter, in the manner of AES-CTR or GCM. The keystream of
+// each block is the first CounterBlockSize bytes of the keystream produced by
+// absorbing a stop, the label "spritz counter", another stop, and then the nonce
+// followed by the block's counter as a 4-byte big-endian integer into the state
+// after key setup; counters wrap around after 2**32-1. The label keeps these
+// keystreams apart from those of Encrypt, NewStreamIV, and EncryptBlock under
+// the same key. A stream
+// created with counter c is therefore at offset CounterBlockSize*(c-b) of a
+// stream created with counter b, which lets formats that record a block index
+// start decrypting at that block without generating the keystream before it.
+// Seek is similarly cheap, since it only needs to generate the keystream from
+// the start of the block containing the new offset. A nonce and counter must
+// never be used to encrypt more than one block's worth of data with the same
+// key. It panics if the nonce is not 12 bytes long.
+func NewStreamNonceCounter(key, nonce []byte, initialCounter uint32) *Stream {
+	if len(nonce) != 12 {
+		panic("spritz: nonce must be 12 bytes")
+	}
+
+	s := &Stream{
+		key:     append([]byte(nil), key...),
+		iv:      append([]byte(nil), nonce...),
+		n:       256,
+		blocks:  true,
+		counter: initialCounter,
+	}
+	s.Reset()
+	return s
+}
+
+// NewStreamDrop returns a new instance of the Spritz cipher using the given key,
+// which discards the first drop output values after key setup, in the manner of
+// the "RC4-drop[n]" mitigation for early keystream bias. Positions and seeks are
+// relative to the first keystream byte after the discarded values, and Reset
+// discards them again. A drop of zero is equivalent to NewStream.
+func NewStreamDrop(key []byte, drop int) *Stream {
+	s := &Stream{
+		key:  append([]byte(nil), key...),
+		n:    256,
+		drop: drop,
+	}
+	s.Reset()
+	return s
+}
+
+// NewStreamRatchet returns a new instance of the Spritz cipher using the given
+// key, which rekeys itself after every interval bytes of keystream to provide
+// forward secrecy. At the end of each interval, a 32-byte key is squeezed from
+// the cipher's state, and the state is re-initialized and keyed with it. The
+// original key is not retained, so later states cannot be used to recover
+// earlier keystream. As a consequence, the cipher cannot be Reset or seek
+// backward.
+func NewStreamRatchet(key []byte, interval int) *Stream {
+	if interval <= 0 {
+		panic("spritz: ratchet interval must be positive")
+	}
+
+	s := &Stream{n: 256, width: 8, ratchet: interval}
+	s.s.initialize(s.n)
+	s.s.keySetup(key)
+	return s
+}
+
+// Keystream returns the first n bytes of the Spritz keystream for the given key.
+func Keystream(key []byte, n int) []byte {
 	var s state
 	s.initialize(256)
+	s.keySetup(key)
 
-	// key setup
-	s.absorb(key)
-	if s.a > 0 {
-		s.shuffle()
+	out := make([]byte, n)
+	s.squeeze(out)
+	return out
+}
+
+// KeystreamEqual reports whether the first n bytes of the Spritz keystreams for
+// keyA and keyB are equal, comparing them in constant time. It is meant for
+// tests of key derivations, which should check that distinct subkeys produce
+// diverging keystreams: equal output for distinct keys is a red flag worth
+// investigating, and becomes less likely to be a coincidence as n grows.
+func KeystreamEqual(keyA, keyB []byte, n int) bool {
+	return Equal(Keystream(keyA, n), Keystream(keyB, n))
+}
+
+func newStream(key, iv []byte, n int) *Stream {
+	s := &Stream{
+		key: append([]byte(nil), key...),
+		iv:  append([]byte(nil), iv...),
+		n:   n,
 	}
-	if iv != nil {
-		s.absorbStop()
-		s.absorb(iv)
+	s.Reset()
+	return s
+}
+
+// Stream is an instance of the Spritz cipher. It implements cipher.Stream.
+type Stream struct {
+	s    state
+	key  []byte
+	iv   []byte
+	salt []byte // absorbed before the key
+	n    int
+	drop int   // number of output values discarded after key setup
+	pos  int64 // number of keystream bytes produced since the last reset
+
+	ratchet int // bytes between rekeyings, or zero for none
+
+	// for NewStreamWithIV, a stop is absorbed after the key even if iv is empty
+	ivStop bool
+
+	// for NewStreamNonceCounter, the keystream is split into blocks
+	blocks  bool   // whether the keystream is split into counter blocks
+	counter uint32 // counter of the first block
+
+	// for N other than 256, output values are packed into bytes
+	width uint   // bits per output value
+	acc   uint64 // buffered keystream bits
+	nacc  uint   // number of buffered bits
+}
+
+// XORKeyStream XORs each byte in the given slice with a byte from the cipher's
+// keystream.
+//
+// As required by cipher.Stream, dst must be at least as long as src, and the two
+// must overlap entirely or not at all.
+func (s *Stream) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("spritz: output smaller than input")
+	}
+	if inexactOverlap(dst[:len(src)], src) {
+		panic("spritz: invalid buffer overlap")
 	}
-	return stream{s: &s}
+	s.xor(dst, src)
 }
 
-type stream struct {
-	s *state
+// XORInPlace XORs each byte in buf with a byte from the cipher's keystream,
+// replacing its contents. It is equivalent to XORKeyStream(buf, buf), and is
+// always safe: the overlap rules of XORKeyStream can't be broken by a single
+// buffer.
+func (s *Stream) XORInPlace(buf []byte) {
+	s.xor(buf, buf)
 }
 
-func (s stream) XORKeyStream(dst, src []byte) {
-	for i, v := range src {
-		dst[i] = v ^ byte(s.s.drip())
+// xor XORs src with the keystream into dst, which must be at least as long and
+// must overlap src entirely or not at all.
+func (s *Stream) xor(dst, src []byte) {
+	for len(src) > 0 {
+		n := s.segment(len(src))
+		if s.n == 256 {
+			xorKeyStream(&s.s, dst[:n], src[:n])
+		} else {
+			for i, v := range src[:n] {
+				dst[i] = v ^ s.keystreamByte()
+			}
+		}
+		s.advance(n)
+		dst, src = dst[n:], src[n:]
 	}
 }
+
+// xorKeyStream XORs src with the keystream of s and writes the result to dst,
+// which must be the same length. The keystream is squeezed into a stack buffer
+// in batches, and each batch is XORed with src in a loop free of bounds checks.
+func xorKeyStream(s *state, dst, src []byte) {
+	var buf [512]byte
+	for len(src) > 0 {
+		ks := buf[:]
+		if len(src) < len(ks) {
+			ks = ks[:len(src)]
+		}
+		s.squeeze(ks)
+
+		d, in := dst[:len(ks)], src[:len(ks)]
+		for i := range ks {
+			d[i] = in[i] ^ ks[i]
+		}
+		dst, src = dst[len(ks):], src[len(ks):]
+	}
+}
+
+// segment returns how many of the next n keystream bytes can be produced before
+// the cipher must ratchet or start a new counter block.
+func (s *Stream) segment(n int) int {
+	if s.ratchet > 0 {
+		if r := int64(s.ratchet) - s.pos%int64(s.ratchet); r < int64(n) {
+			return int(r)
+		}
+	}
+	if s.blocks {
+		if r := CounterBlockSize - s.pos%CounterBlockSize; r < int64(n) {
+			return int(r)
+		}
+	}
+	return n
+}
+
+// advance records that n keystream bytes have been produced, and ratchets the
+// cipher or starts a new counter block if it has reached the end of one.
+func (s *Stream) advance(n int) {
+	s.pos += int64(n)
+	if n > 0 && s.blocks && s.pos%CounterBlockSize == 0 {
+		s.startBlock()
+	}
+	if n > 0 && s.ratchet > 0 && s.pos%int64(s.ratchet) == 0 {
+		key := make([]byte, 32)
+		s.s.squeeze(key)
+		s.s.initialize(s.n)
+		s.s.keySetup(key)
+		for i := range key {
+			key[i] = 0
+		}
+		s.acc, s.nacc = 0, 0
+	}
+}
+
+// startBlock keys the cipher for the counter block containing its position.
+func (s *Stream) startBlock() {
+	var iv [16]byte
+	n := copy(iv[:], s.iv)
+	binary.BigEndian.PutUint32(iv[n:], s.counter+uint32(s.pos/CounterBlockSize))
+
+	s.s.initialize(s.n)
+	s.s.keySetup(s.key)
+	s.s.absorbLabel(counterLabel)
+	s.s.absorb(iv[:n+4])
+}
+
+// counterLabel separates the keystreams of counter blocks from those of other
+// modes.
+const counterLabel = "spritz counter"
+
+// keystreamByte returns the next byte of the keystream, packing output values
+// as described by NewStreamN.
+func (s *Stream) keystreamByte() byte {
+	if s.n == 256 {
+		return byte(s.s.drip())
+	}
+	return packedByte(&s.s, s.width, &s.acc, &s.nacc)
+}
+
+// packedByte returns the next byte of the bit string formed from the output
+// values of s below 2**width, width bits at a time, buffering leftover bits in
+// acc and nacc.
+func packedByte(s *state, width uint, acc *uint64, nacc *uint) byte {
+	for *nacc < 8 {
+		v := s.drip()
+		if v >= 1<<width {
+			continue // discard values which would bias the keystream
+		}
+		*acc |= uint64(v) << *nacc
+		*nacc += width
+	}
+
+	b := byte(*acc)
+	*acc >>= 8
+	*nacc -= 8
+	return b
+}
+
+// FillKeystream overwrites buf with the next len(buf) bytes of the cipher's
+// keystream. It is equivalent to XORKeyStream with an all-zero source.
+func (s *Stream) FillKeystream(buf []byte) {
+	for len(buf) > 0 {
+		n := s.segment(len(buf))
+		for i := range buf[:n] {
+			buf[i] = s.keystreamByte()
+		}
+		s.advance(n)
+		buf = buf[n:]
+	}
+}
+
+// Clone returns an independent copy of the cipher at its current position in
+// the keystream. This is the supported way to produce two independent
+// keystreams from the same keyed starting point: advancing one copy does not
+// affect the other.
+func (s *Stream) Clone() *Stream {
+	c := *s
+	c.s = *s.s.clone()
+	c.key = append([]byte(nil), s.key...)
+	c.iv = append([]byte(nil), s.iv...)
+	c.salt = append([]byte(nil), s.salt...)
+	return &c
+}
+
+// Reseed mixes extra into the cipher's current state, so that all subsequent
+// keystream depends on both the prior state and extra. This is forward mixing,
+// not a reset: the keystream already produced is unaffected, and Reset (or
+// seeking backwards) returns the cipher to its original, un-reseeded keystream.
+// It panics if the cipher was created with NewStreamNonceCounter, which rekeys
+// at the start of every block and so would silently drop extra.
+func (s *Stream) Reseed(extra []byte) {
+	if s.blocks {
+		panic("spritz: Reseed of a counter stream")
+	}
+	s.s.absorbStop()
+	s.s.absorb(extra)
+	s.s.shuffle()
+}
+
+// Reset returns the cipher to the start of its keystream, as if it had just
+// been created with its original key and IV. It panics if the cipher was
+// created with NewStreamRatchet, which does not retain its original key.
+func (s *Stream) Reset() {
+	if s.ratchet > 0 {
+		panic("spritz: Reset of a ratcheting stream")
+	}
+	if s.blocks {
+		s.pos = 0
+		s.startBlock()
+		s.width = 8
+		return
+	}
+	s.s.initialize(s.n)
+	if len(s.salt) > 0 {
+		s.s.absorb(s.salt)
+		s.s.absorbStop()
+	}
+	s.s.keySetup(s.key)
+	if len(s.iv) > 0 || s.ivStop {
+		s.s.absorbStop()
+		s.s.absorb(s.iv)
+	}
+	for i := 0; i < s.drop; i++ {
+		s.s.drip()
+	}
+	s.pos = 0
+	s.width = uint(bits.Len(uint(s.n)) - 1)
+	s.acc, s.nacc = 0, 0
+}
+
+// Rekey returns the cipher to the start of the keystream for a new key, as if it
+// had just been created with NewStreamN using that key and its current state
+// size; any IV, salt, drop, ratchet interval, or counter is discarded. It reuses the
+// cipher's existing memory, so rekeying a cipher between uses does not allocate
+// unless the new key is longer than any it has held before.
+func (s *Stream) Rekey(key []byte) {
+	s.key = append(s.key[:0], key...)
+	s.iv = s.iv[:0]
+	s.ivStop = false
+	s.salt = s.salt[:0]
+	s.drop = 0
+	s.ratchet = 0
+	s.blocks, s.counter = false, 0
+	s.Reset()
+}
+
+var (
+	errWhence    = errors.New("spritz: invalid whence")
+	errNegOffset = errors.New("spritz: negative position")
+
+	errRatchetSeek = errors.New("spritz: cannot seek backward in a ratcheting stream")
+)
+
+// Seek moves the cipher to the given offset in its keystream, interpreted
+// according to whence as either relative to the start of the keystream
+// (io.SeekStart) or to the current position (io.SeekCurrent). The keystream is
+// infinite, so io.SeekEnd is not supported.
+//
+// Spritz has no way to jump ahead directly, so seeking forward generates and
+// discards every keystream byte in between, and seeking backward resets the
+// cipher and generates every keystream byte from the start. Streams created
+// with NewStreamNonceCounter only generate the keystream from the start of the
+// block holding the new offset.
+func (s *Stream) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.pos
+	default:
+		return s.pos, errWhence
+	}
+
+	if offset < 0 {
+		return s.pos, errNegOffset
+	}
+
+	if s.blocks {
+		// jump straight to the start of the block holding the new offset
+		s.pos = offset - offset%CounterBlockSize
+		s.startBlock()
+	} else if offset < s.pos {
+		if s.ratchet > 0 {
+			return s.pos, errRatchetSeek
+		}
+		s.Reset()
+	}
+	for s.pos < offset {
+		s.keystreamByte()
+		s.advance(1)
+	}
+	return s.pos, nil
+}
+
+// Position returns the number of keystream bytes the cipher has produced since
+// it was created or last Reset, which is the offset Seek(0, io.SeekCurrent)
+// would return. Every byte of keystream counts, whether it was consumed by
+// XORKeyStream, FillKeystream, or Seek. For N=256 this is also the number of
+// Spritz output values consumed, not counting any dropped by NewStreamDrop.
+func (s *Stream) Position() int64 {
+	return s.pos
+}
+
+// String returns a description of the cipher for debugging, which includes its
+// state size, position, and any drop or ratchet interval, but never its key, IV,
+// or any part of its state.
+func (s *Stream) String() string {
+	desc := fmt.Sprintf("spritz.Stream{N: %d, position: %d", s.n, s.pos)
+	if s.drop > 0 {
+		desc += fmt.Sprintf(", drop: %d", s.drop)
+	}
+	if s.ratchet > 0 {
+		desc += fmt.Sprintf(", ratchet: %d", s.ratchet)
+	}
+	if s.blocks {
+		desc += fmt.Sprintf(", counter: %d", s.counter)
+	}
+	return desc + "}"
+}
+
+// Wipe zeroes the cipher's state, key, and IV. After Wipe, the cipher must be
+// re-initialized with Reset before it is used again, and will then behave as if
+// it had been created with an all-zero key and IV of the same lengths.
+func (s *Stream) Wipe() {
+	s.s.wipe()
+	for i := range s.key {
+		s.key[i] = 0
+	}
+	for i := range s.iv {
+		s.iv[i] = 0
+	}
+	for i := range s.salt {
+		s.salt[i] = 0
+	}
+	s.pos = 0
+	s.acc, s.nacc = 0, 0
+}
+
+// inexactOverlap reports whether x and y share memory at any non-corresponding
+// index. Slices which start at the same address are allowed to overlap.
+func inexactOverlap(x, y []byte) bool {
+	if len(x) == 0 || len(y) == 0 || &x[0] == &y[0] {
+		return false
+	}
+	return uintptr(unsafe.Pointer(&x[0])) <= uintptr(unsafe.Pointer(&y[len(y)-1])) &&
+		uintptr(unsafe.Pointer(&y[0])) <= uintptr(unsafe.Pointer(&x[len(x)-1]))
+}
+
+// MarshalBinary encodes the cipher's key, IV, salt, discard count, ratchet
+// interval, initial counter, position, buffered keystream, and full state,
+// allowing it to be resumed later with UnmarshalBinary.
+func (s *Stream) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, 8*(18+s.n)+len(s.key)+len(s.iv)+len(s.salt))
+	b = appendBytes(b, s.key)
+	b = appendBytes(b, s.iv)
+	b = appendBytes(b, s.salt)
+	b = appendUint64(b, s.drop)
+	b = appendUint64(b, s.ratchet)
+	if s.blocks {
+		b = appendUint64(b, 1)
+	} else {
+		b = appendUint64(b, 0)
+	}
+	b = appendUint64(b, int(s.counter))
+	if s.ivStop {
+		b = appendUint64(b, 1)
+	} else {
+		b = appendUint64(b, 0)
+	}
+	b = appendUint64(b, int(s.pos))
+	b = appendUint64(b, int(s.nacc))
+	b = appendUint64(b, int(s.acc))
+	return appendState(b, &s.s), nil
+}
+
+// UnmarshalBinary restores a cipher previously encoded with MarshalBinary,
+// replacing its key, IV, salt, discard count, ratchet interval, initial
+// counter, position, buffered keystream, and state.
+func (s *Stream) UnmarshalBinary(b []byte) error {
+	key, b, err := consumeBytes(b)
+	if err != nil {
+		return err
+	}
+
+	iv, b, err := consumeBytes(b)
+	if err != nil {
+		return err
+	}
+
+	salt, b, err := consumeBytes(b)
+	if err != nil {
+		return err
+	}
+
+	drop, b, err := consumeUint64(b)
+	if err != nil {
+		return err
+	}
+	if drop < 0 {
+		return errStateValue
+	}
+
+	ratchet, b, err := consumeUint64(b)
+	if err != nil {
+		return err
+	}
+	if ratchet < 0 {
+		return errStateValue
+	}
+
+	blocks, b, err := consumeUint64(b)
+	if err != nil {
+		return err
+	}
+	counter, b, err := consumeUint64(b)
+	if err != nil {
+		return err
+	}
+	if blocks < 0 || blocks > 1 || counter < 0 || uint64(counter) > 1<<32-1 {
+		return errStateValue
+	}
+	if blocks == 1 && len(iv) != 12 {
+		return errStateValue // counter blocks need a 12-byte nonce
+	}
+
+	ivStop, b, err := consumeUint64(b)
+	if err != nil {
+		return err
+	}
+	if ivStop < 0 || ivStop > 1 {
+		return errStateValue
+	}
+
+	pos, b, err := consumeUint64(b)
+	if err != nil {
+		return err
+	}
+	if pos < 0 {
+		return errStateValue
+	}
+
+	nacc, b, err := consumeUint64(b)
+	if err != nil {
+		return err
+	}
+	acc, b, err := consumeUint64(b)
+	if err != nil {
+		return err
+	}
+	if nacc < 0 || nacc >= 64 || uint64(acc)>>uint(nacc) != 0 {
+		return errStateValue
+	}
+
+	st, err := unmarshalState(b)
+	if err != nil {
+		return err
+	}
+	if blocks == 1 && st.n != 256 {
+		return errStateValue
+	}
+
+	s.key, s.iv, s.salt, s.n, s.drop, s.ratchet, s.pos, s.s = key, iv, salt, st.n, drop, ratchet, int64(pos), st
+	s.blocks, s.counter = blocks == 1, uint32(counter)
+	s.ivStop = ivStop == 1
+	s.width = uint(bits.Len(uint(s.n)) - 1)
+	s.acc, s.nacc = uint64(acc), uint(nacc)
+	return nil
+}
@@ -2,6 +2,10 @@ package spritz_test
 
 import (
 	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
 	"testing"
 
 	"github.com/codahale/spritz"
@@ -31,6 +35,993 @@ func TestStream(t *testing.T) {
 	}
 }
 
+func TestStreamIV(t *testing.T) {
+	key := []byte("arcfour")
+
+	a := make([]byte, 16)
+	spritz.NewStreamIV(key, []byte("one")).XORKeyStream(a, a)
+
+	b := make([]byte, 16)
+	spritz.NewStreamIV(key, []byte("two")).XORKeyStream(b, b)
+
+	if bytes.Equal(a, b) {
+		t.Errorf("Different IVs produced the same keystream: %x", a)
+	}
+
+	c := make([]byte, 16)
+	spritz.NewStreamIV(key, []byte{}).XORKeyStream(c, c)
+
+	d := make([]byte, 16)
+	spritz.NewStream(key).XORKeyStream(d, d)
+
+	if !bytes.Equal(c, d) {
+		t.Errorf("Output for an empty IV was \n%x\n but expected\n%x", c, d)
+	}
+}
+
+func TestStreamWithIVEmpty(t *testing.T) {
+	// a non-nil empty IV absorbs a stop after the key
+	key := []byte("arcfour")
+	expected := []byte{0xd5, 0xee, 0x27, 0x90, 0x5e, 0x97, 0x91, 0xc4}
+
+	s := spritz.NewStreamWithIV(key, []byte{})
+	out := make([]byte, len(expected))
+	s.XORKeyStream(out, out)
+	if !bytes.Equal(out, expected) {
+		t.Errorf("Output was \n%x\n but expected\n%x", out, expected)
+	}
+
+	state, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := spritz.NewStream(nil)
+	if err := r.UnmarshalBinary(state); err != nil {
+		t.Fatal(err)
+	}
+	r.Reset()
+	r.XORKeyStream(out, make([]byte, len(out)))
+	if !bytes.Equal(out, expected) {
+		t.Errorf("Output after unmarshaling was \n%x\n but expected\n%x", out, expected)
+	}
+
+	plain := spritz.NewStreamWithIV(key, nil)
+	plain.XORKeyStream(out, make([]byte, len(out)))
+	if expected := spritz.Keystream(key, len(out)); !bytes.Equal(out, expected) {
+		t.Errorf("Output for a nil IV was \n%x\n but expected\n%x", out, expected)
+	}
+}
+
+func TestStreamN(t *testing.T) {
+	key := []byte("arcfour")
+
+	a := make([]byte, 16)
+	spritz.NewStreamN(key, 256).XORKeyStream(a, a)
+
+	b := make([]byte, 16)
+	spritz.NewStream(key).XORKeyStream(b, b)
+
+	if !bytes.Equal(a, b) {
+		t.Errorf("Output for N=256 was \n%x\n but expected\n%x", a, b)
+	}
+
+	c := make([]byte, 16)
+	spritz.NewStreamN(key, 512).XORKeyStream(c, c)
+
+	if bytes.Equal(a, c) {
+		t.Errorf("N=512 produced the same keystream as N=256: %x", c)
+	}
+}
+
+func TestStreamNTooSmall(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Created a stream with N=8")
+		}
+	}()
+
+	spritz.NewStreamN([]byte("arcfour"), 8)
+}
+
+func TestStreamReset(t *testing.T) {
+	s := spritz.NewStreamIV([]byte("arcfour"), []byte("iv"))
+
+	a := make([]byte, 16)
+	s.XORKeyStream(a, a)
+
+	s.Reset()
+
+	b := make([]byte, 16)
+	s.XORKeyStream(b, b)
+
+	if !bytes.Equal(a, b) {
+		t.Errorf("Output after Reset was \n%x\n but expected\n%x", b, a)
+	}
+}
+
+func TestStreamRekey(t *testing.T) {
+	for _, n := range []int{256, 512} {
+		s := spritz.NewStreamN([]byte("spam"), n)
+		s.XORKeyStream(make([]byte, 10), make([]byte, 10))
+		s.Rekey([]byte("arcfour"))
+
+		out := make([]byte, 32)
+		s.XORKeyStream(out, out)
+
+		expected := make([]byte, 32)
+		spritz.NewStreamN([]byte("arcfour"), n).XORKeyStream(expected, expected)
+
+		if !bytes.Equal(out, expected) {
+			t.Errorf("Output for N=%d was \n%x\n but expected\n%x", n, out, expected)
+		}
+	}
+
+	s := spritz.NewStreamIV([]byte("arcfour"), []byte("iv"))
+	key := []byte("spam")
+	if allocs := testing.AllocsPerRun(10, func() { s.Rekey(key) }); allocs != 0 {
+		t.Errorf("Rekey allocated %v times", allocs)
+	}
+}
+
+func TestStreamSeek(t *testing.T) {
+	key := []byte("arcfour")
+	expected := make([]byte, 64)
+	spritz.NewStream(key).XORKeyStream(expected, expected)
+
+	fixtures := []struct {
+		offset int64
+		whence int
+		pos    int64
+	}{
+		{10, io.SeekStart, 10},
+		{0, io.SeekCurrent, 10},
+		{20, io.SeekCurrent, 30},
+		{5, io.SeekStart, 5},
+		{-3, io.SeekCurrent, 2},
+	}
+
+	s := spritz.NewStream(key)
+	for _, f := range fixtures {
+		pos, err := s.Seek(f.offset, f.whence)
+		if err != nil || pos != f.pos {
+			t.Fatalf("Seek(%d, %d) returned %d, %v but expected %d", f.offset, f.whence, pos, err, f.pos)
+		}
+
+		out := make([]byte, 8)
+		s.XORKeyStream(out, out)
+		if !bytes.Equal(out, expected[pos:pos+8]) {
+			t.Errorf("Output at %d was \n%x\n but expected\n%x", pos, out, expected[pos:pos+8])
+		}
+
+		if _, err := s.Seek(pos, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestStreamPosition(t *testing.T) {
+	s := spritz.NewStream([]byte("arcfour"))
+	if p := s.Position(); p != 0 {
+		t.Errorf("Position of a new stream was %d", p)
+	}
+
+	s.XORKeyStream(make([]byte, 10), make([]byte, 10))
+	s.FillKeystream(make([]byte, 5))
+	if p := s.Position(); p != 15 {
+		t.Errorf("Position was %d but expected 15", p)
+	}
+
+	if _, err := s.Seek(100, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if p := s.Position(); p != 100 {
+		t.Errorf("Position after Seek was %d but expected 100", p)
+	}
+
+	s.Reset()
+	if p := s.Position(); p != 0 {
+		t.Errorf("Position after Reset was %d", p)
+	}
+}
+
+func TestStreamString(t *testing.T) {
+	key := []byte("arcfour")
+	s := spritz.NewStreamDrop(key, 768)
+	s.XORKeyStream(make([]byte, 10), make([]byte, 10))
+
+	str := fmt.Sprint(s)
+	if expected := "spritz.Stream{N: 256, position: 10, drop: 768}"; str != expected {
+		t.Errorf("String was %q but expected %q", str, expected)
+	}
+
+	if strings.Contains(str, string(key)) || strings.Contains(str, fmt.Sprintf("%x", key)) {
+		t.Errorf("String revealed the key: %q", str)
+	}
+}
+
+func TestStreamSeekErrors(t *testing.T) {
+	s := spritz.NewStream([]byte("arcfour"))
+
+	if _, err := s.Seek(-1, io.SeekStart); err == nil {
+		t.Error("Seeked to a negative position")
+	}
+
+	if _, err := s.Seek(0, io.SeekEnd); err == nil {
+		t.Error("Seeked relative to the end of the keystream")
+	}
+}
+
+func TestStreamWipe(t *testing.T) {
+	s := spritz.NewStream([]byte("arcfour"))
+	s.Wipe()
+	s.Reset()
+
+	out := make([]byte, 16)
+	s.XORKeyStream(out, out)
+
+	expected := make([]byte, 16)
+	spritz.NewStream(make([]byte, 7)).XORKeyStream(expected, expected)
+
+	if !bytes.Equal(out, expected) {
+		t.Errorf("Output after Wipe and Reset was \n%x\n but expected\n%x", out, expected)
+	}
+}
+
+func TestStreamChecked(t *testing.T) {
+	if _, err := spritz.NewStreamChecked(nil); err == nil {
+		t.Error("Created a stream with an empty key")
+	}
+
+	s, err := spritz.NewStreamChecked([]byte("arcfour"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := make([]byte, 16)
+	s.XORKeyStream(out, out)
+
+	expected := make([]byte, 16)
+	spritz.NewStream([]byte("arcfour")).XORKeyStream(expected, expected)
+
+	if !bytes.Equal(out, expected) {
+		t.Errorf("Output was \n%x\n but expected\n%x", out, expected)
+	}
+}
+
+func TestStreamNChecked(t *testing.T) {
+	if _, err := spritz.NewStreamNChecked(nil, 256); err != spritz.ErrEmptyKey {
+		t.Errorf("Created a stream with an empty key: %v", err)
+	}
+
+	if _, err := spritz.NewStreamNChecked([]byte("arcfour"), 8); err != spritz.ErrInvalidN {
+		t.Errorf("Created a stream with N=8: %v", err)
+	}
+
+	s, err := spritz.NewStreamNChecked([]byte("arcfour"), 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := make([]byte, 16)
+	s.XORKeyStream(out, out)
+
+	expected := make([]byte, 16)
+	spritz.NewStreamN([]byte("arcfour"), 512).XORKeyStream(expected, expected)
+
+	if !bytes.Equal(out, expected) {
+		t.Errorf("Output was \n%x\n but expected\n%x", out, expected)
+	}
+}
+
+func TestStreamCanonical(t *testing.T) {
+	fixtures := []struct {
+		name string
+		key  []byte
+		err  error
+	}{
+		{"an empty key", nil, spritz.ErrEmptyKey},
+		{"a weak key", make([]byte, 32), spritz.ErrWeakKey},
+		{"a long weak key", bytes.Repeat([]byte{'k'}, 2*spritz.MaxKeySize), spritz.ErrWeakKey},
+	}
+
+	for _, f := range fixtures {
+		if _, err := spritz.NewStreamCanonical(f.key); err != f.err {
+			t.Errorf("Created a stream with %s: %v", f.name, err)
+		}
+	}
+
+	key := make([]byte, 10*1024)
+	for i := range key {
+		key[i] = byte(i)
+	}
+
+	s, err := spritz.NewStreamCanonical(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := make([]byte, 16)
+	s.XORKeyStream(out, out)
+
+	h := spritz.Sum256(key)
+	expected := spritz.Keystream(h[:], 16)
+	if !bytes.Equal(out, expected) {
+		t.Errorf("Output was \n%x\n but expected\n%x", out, expected)
+	}
+
+	if known := []byte{
+		0x8b, 0x64, 0xd7, 0x8c, 0x58, 0xd2, 0x14, 0x05,
+		0xdf, 0xed, 0x4a, 0xe1, 0x48, 0x36, 0xf9, 0xe8,
+	}; !bytes.Equal(out, known) {
+		t.Errorf("Output was \n%x\n but expected\n%x", out, known)
+	}
+
+	short := key[:spritz.MaxKeySize]
+	if s, err = spritz.NewStreamCanonical(short); err != nil {
+		t.Fatal(err)
+	}
+	s.XORKeyStream(out, make([]byte, 16))
+
+	if expected := spritz.Keystream(short, 16); !bytes.Equal(out, expected) {
+		t.Errorf("Output was \n%x\n but expected\n%x", out, expected)
+	}
+}
+
+func TestStreamClone(t *testing.T) {
+	key := []byte("arcfour")
+	expected := make([]byte, 32)
+	spritz.NewStream(key).XORKeyStream(expected, expected)
+
+	a := spritz.NewStream(key)
+	skip := make([]byte, 8)
+	a.XORKeyStream(skip, skip)
+	b := a.Clone()
+
+	outA := make([]byte, 24)
+	a.XORKeyStream(outA, outA)
+
+	outB := make([]byte, 24)
+	b.XORKeyStream(outB, outB)
+
+	if !bytes.Equal(outA, expected[8:]) {
+		t.Errorf("Output for original was \n%x\n but expected\n%x", outA, expected[8:])
+	}
+
+	if !bytes.Equal(outB, expected[8:]) {
+		t.Errorf("Output for clone was \n%x\n but expected\n%x", outB, expected[8:])
+	}
+}
+
+func TestKeystream(t *testing.T) {
+	key := []byte("arcfour")
+	out := spritz.Keystream(key, 100)
+
+	expected := make([]byte, 100)
+	if _, err := io.ReadFull(spritz.NewReader(key), expected); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(out, expected) {
+		t.Errorf("Output for %q was \n%x\n but expected\n%x", key, out, expected)
+	}
+}
+
+func TestKeystreamEqual(t *testing.T) {
+	fixtures := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"arcfour", "arcfour", true},
+		{"arcfour", "arcfour2", false},
+		{"ABC", "spam", false},
+	}
+
+	for _, f := range fixtures {
+		if v := spritz.KeystreamEqual([]byte(f.a), []byte(f.b), 32); v != f.expected {
+			t.Errorf("Keystreams for %q and %q were equal: %v, expected %v", f.a, f.b, v, f.expected)
+		}
+	}
+
+	if !spritz.KeystreamEqual([]byte("ABC"), []byte("spam"), 0) {
+		t.Error("Empty keystreams were not equal")
+	}
+}
+
+func TestStreamOverlap(t *testing.T) {
+	key := []byte("arcfour")
+	msg := []byte("hello world, this is a message")
+
+	expected := make([]byte, len(msg))
+	spritz.NewStream(key).XORKeyStream(expected, msg)
+
+	inPlace := append([]byte(nil), msg...)
+	spritz.NewStream(key).XORKeyStream(inPlace, inPlace)
+	if !bytes.Equal(inPlace, expected) {
+		t.Errorf("In-place output was \n%x\n but expected\n%x", inPlace, expected)
+	}
+
+	buf := make([]byte, 2*len(msg))
+	copy(buf, msg)
+	spritz.NewStream(key).XORKeyStream(buf[len(msg):], buf[:len(msg)])
+	if !bytes.Equal(buf[len(msg):], expected) {
+		t.Errorf("Disjoint output was \n%x\n but expected\n%x", buf[len(msg):], expected)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Encrypted partially overlapping buffers")
+		}
+	}()
+	spritz.NewStream(key).XORKeyStream(buf[1:], buf[:len(msg)])
+}
+
+func TestStreamXORInPlace(t *testing.T) {
+	key := []byte("arcfour")
+	msg := []byte(strings.Repeat("hello world, this is a message", 50))
+
+	fixtures := []struct {
+		name string
+		new  func() *spritz.Stream
+	}{
+		{"N=256", func() *spritz.Stream { return spritz.NewStream(key) }},
+		{"N=300", func() *spritz.Stream { return spritz.NewStreamN(key, 300) }},
+		{"ratchet", func() *spritz.Stream { return spritz.NewStreamRatchet(key, 100) }},
+	}
+
+	for _, f := range fixtures {
+		expected := make([]byte, len(msg))
+		f.new().XORKeyStream(expected, msg)
+
+		buf := append([]byte(nil), msg...)
+		s := f.new()
+		s.XORInPlace(buf[:777])
+		s.XORInPlace(buf[777:])
+		if !bytes.Equal(buf, expected) {
+			t.Errorf("%s: Output was \n%x\n but expected\n%x", f.name, buf, expected)
+		}
+	}
+}
+
+func TestStreamShortOutput(t *testing.T) {
+	defer func() {
+		if r := recover(); r != "spritz: output smaller than input" {
+			t.Errorf("Recovered %v", r)
+		}
+	}()
+
+	spritz.NewStream([]byte("arcfour")).XORKeyStream(make([]byte, 1), make([]byte, 2))
+}
+
+func TestStreamReseed(t *testing.T) {
+	key := []byte("arcfour")
+
+	a := spritz.NewStream(key)
+	b := spritz.NewStream(key)
+	b.Reseed([]byte("entropy"))
+
+	outA := make([]byte, 64)
+	a.XORKeyStream(outA, outA)
+
+	outB := make([]byte, 64)
+	b.XORKeyStream(outB, outB)
+
+	for i := 0; i < len(outA); i += 8 {
+		if bytes.Equal(outA[i:i+8], outB[i:i+8]) {
+			t.Errorf("Reseeded output at %d matched original: %x", i, outA[i:i+8])
+		}
+	}
+
+	c := spritz.NewStream(key)
+	c.Reseed([]byte("entropy"))
+
+	outC := make([]byte, 64)
+	c.XORKeyStream(outC, outC)
+
+	if !bytes.Equal(outB, outC) {
+		t.Errorf("Output was \n%x\n but expected\n%x", outC, outB)
+	}
+}
+
+func TestStreamReseedCounter(t *testing.T) {
+	s := spritz.NewStreamNonceCounter([]byte("arcfour"), make([]byte, 12), 0)
+
+	defer func() {
+		if r := recover(); r != "spritz: Reseed of a counter stream" {
+			t.Errorf("Recovered %v", r)
+		}
+	}()
+	s.Reseed([]byte("entropy"))
+}
+
+func TestStreamFillKeystream(t *testing.T) {
+	key := []byte("arcfour")
+
+	out := []byte("some non-zero data")
+	s := spritz.NewStream(key)
+	s.FillKeystream(out)
+
+	expected := make([]byte, len(out))
+	spritz.NewStream(key).XORKeyStream(expected, expected)
+
+	if !bytes.Equal(out, expected) {
+		t.Errorf("Output was \n%x\n but expected\n%x", out, expected)
+	}
+
+	if pos, _ := s.Seek(0, io.SeekCurrent); pos != int64(len(out)) {
+		t.Errorf("Position was %d but expected %d", pos, len(out))
+	}
+}
+
+func TestStreamMarshalBinary(t *testing.T) {
+	key, iv := []byte("arcfour"), []byte("iv")
+	expected := make([]byte, 64)
+	spritz.NewStreamIV(key, iv).XORKeyStream(expected, expected)
+
+	a := spritz.NewStreamIV(key, iv)
+	out := make([]byte, 64)
+	a.XORKeyStream(out[:20], out[:20])
+
+	state, err := a.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := spritz.NewStream([]byte("other"))
+	if err := b.UnmarshalBinary(state); err != nil {
+		t.Fatal(err)
+	}
+	b.XORKeyStream(out[20:], out[20:])
+
+	if !bytes.Equal(out, expected) {
+		t.Errorf("Output was \n%x\n but expected\n%x", out, expected)
+	}
+
+	if _, err := b.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	b.XORKeyStream(out, make([]byte, len(out)))
+
+	if !bytes.Equal(out, expected) {
+		t.Errorf("Output after seeking was \n%x\n but expected\n%x", out, expected)
+	}
+}
+
+func TestStreamUnmarshalBinaryCorrupt(t *testing.T) {
+	state, err := spritz.NewStream([]byte("arcfour")).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, n := range []int{0, 8, 30, len(state) - 1} {
+		if err := spritz.NewStream(nil).UnmarshalBinary(state[:n]); err == nil {
+			t.Errorf("Unmarshaled state truncated to %d bytes", n)
+		}
+	}
+}
+
+func TestStreamUnmarshalBinaryBadCounter(t *testing.T) {
+	key := []byte("arcfour")
+
+	// counter blocks are only valid with a 12-byte nonce and N=256, so flip the
+	// blocks field of streams which have neither
+	fixtures := []struct {
+		name string
+		s    *spritz.Stream
+		ivs  int
+	}{
+		{"a 13-byte IV", spritz.NewStreamIV(key, make([]byte, 13)), 13},
+		{"N=512", spritz.NewStreamN(key, 512), 0},
+	}
+
+	for _, f := range fixtures {
+		state, err := f.s.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		blocks := 8 + len(key) + 8 + f.ivs + 8 + 8 + 8
+		state[blocks+7] = 1
+
+		if err := spritz.NewStream(nil).UnmarshalBinary(state); err == nil {
+			t.Errorf("Unmarshaled a counter stream with %s", f.name)
+		}
+	}
+}
+
+func TestStreamNOutput(t *testing.T) {
+	for _, n := range []int{16, 100, 300, 512, 1024} {
+		key := []byte("arcfour")
+
+		all := make([]byte, 4096)
+		spritz.NewStreamN(key, n).XORKeyStream(all, all)
+
+		if n != 256 {
+			// every keystream byte is packed from values below 2**b
+			sp := spritz.NewSponge(n)
+			sp.Absorb(key)
+			if expected := sp.SqueezeBytes(len(all)); !bytes.Equal(all, expected) {
+				t.Errorf("N=%d: keystream was not packed from unbiased values", n)
+			}
+		}
+
+		seen := make(map[byte]bool)
+		for _, v := range all {
+			seen[v] = true
+		}
+		if len(seen) != 256 {
+			t.Errorf("N=%d: keystream only contained %d distinct byte values", n, len(seen))
+		}
+
+		s := spritz.NewStreamN(key, n)
+		parts := make([]byte, len(all))
+		for i := 0; i < len(parts); i += 7 {
+			end := i + 7
+			if end > len(parts) {
+				end = len(parts)
+			}
+			s.XORKeyStream(parts[i:end], parts[i:end])
+		}
+		if !bytes.Equal(parts, all) {
+			t.Errorf("N=%d: output in parts differed from output in one call", n)
+		}
+
+		state, err := s.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Seek(3, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.UnmarshalBinary(state); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Seek(1001, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		out := make([]byte, 10)
+		s.XORKeyStream(out, out)
+		if !bytes.Equal(out, all[1001:1011]) {
+			t.Errorf("N=%d: output at 1001 was \n%x\n but expected\n%x", n, out, all[1001:1011])
+		}
+	}
+}
+
+func TestStreamSalted(t *testing.T) {
+	key := []byte("arcfour")
+
+	s := spritz.NewSponge(256)
+	s.Absorb([]byte("salt"))
+	s.AbsorbStop()
+	s.Absorb(key)
+	expected := make([]byte, 32)
+	s.Squeeze(expected)
+
+	out := make([]byte, 32)
+	a := spritz.NewStreamSalted(key, []byte("salt"))
+	a.XORKeyStream(out, out)
+	if !bytes.Equal(out, expected) {
+		t.Errorf("Output was \n%x\n but expected\n%x", out, expected)
+	}
+
+	a.Reset()
+	a.XORKeyStream(out, make([]byte, 32))
+	if !bytes.Equal(out, expected) {
+		t.Errorf("Output after Reset was \n%x\n but expected\n%x", out, expected)
+	}
+
+	state, err := a.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := spritz.NewStream([]byte("other"))
+	if err := b.UnmarshalBinary(state); err != nil {
+		t.Fatal(err)
+	}
+	b.Reset()
+	b.XORKeyStream(out, make([]byte, 32))
+	if !bytes.Equal(out, expected) {
+		t.Errorf("Output after unmarshaling and Reset was \n%x\n but expected\n%x", out, expected)
+	}
+
+	other := make([]byte, 32)
+	spritz.NewStreamSalted(key, []byte("pepper")).XORKeyStream(other, other)
+	if bytes.Equal(out, other) {
+		t.Errorf("Different salts produced the same keystream: %x", out)
+	}
+
+	unsalted := make([]byte, 32)
+	spritz.NewStreamSalted(key, nil).XORKeyStream(unsalted, unsalted)
+	if expected := spritz.Keystream(key, 32); !bytes.Equal(unsalted, expected) {
+		t.Errorf("Output with an empty salt was \n%x\n but expected\n%x", unsalted, expected)
+	}
+}
+
+func TestStreamDrop(t *testing.T) {
+	key := []byte("arcfour")
+	expected := spritz.Keystream(key, 64)
+
+	for _, drop := range []int{0, 1, 10, 32} {
+		s := spritz.NewStreamDrop(key, drop)
+		out := make([]byte, 64-drop)
+		s.XORKeyStream(out, out)
+
+		if !bytes.Equal(out, expected[drop:]) {
+			t.Errorf("Output for drop=%d was \n%x\n but expected\n%x", drop, out, expected[drop:])
+		}
+
+		s.Reset()
+		s.XORKeyStream(out, make([]byte, len(out)))
+
+		if !bytes.Equal(out, expected[drop:]) {
+			t.Errorf("Output for drop=%d after Reset was \n%x\n but expected\n%x", drop, out, expected[drop:])
+		}
+	}
+}
+
+func TestEncrypt(t *testing.T) {
+	key, nonce := []byte("arcfour"), []byte("nonce")
+	plaintext := []byte("hello world")
+	original := append([]byte(nil), plaintext...)
+
+	ciphertext := spritz.Encrypt(key, nonce, plaintext)
+	if !bytes.Equal(plaintext, original) {
+		t.Errorf("Encrypt modified its input: %q", plaintext)
+	}
+
+	expected := make([]byte, len(plaintext))
+	spritz.NewStreamIV(key, nonce).XORKeyStream(expected, plaintext)
+	if !bytes.Equal(ciphertext, expected) {
+		t.Errorf("Ciphertext was \n%x\n but expected\n%x", ciphertext, expected)
+	}
+
+	if out := spritz.Decrypt(key, nonce, ciphertext); !bytes.Equal(out, plaintext) {
+		t.Errorf("Decrypted %q but expected %q", out, plaintext)
+	}
+
+	if out := spritz.Encrypt(key, []byte("other"), plaintext); bytes.Equal(out, ciphertext) {
+		t.Errorf("Different nonces produced the same ciphertext: %x", out)
+	}
+}
+
+func TestTransformer(t *testing.T) {
+	key, nonce := []byte("arcfour"), []byte("nonce")
+	msg := []byte("hello world, in several pieces")
+
+	encrypt := spritz.NewTransformer(key, nonce)
+	var ciphertext []byte
+	for _, piece := range [][]byte{msg[:5], nil, msg[5:12], {}, msg[12:]} {
+		ciphertext = append(ciphertext, encrypt(piece)...)
+	}
+
+	if expected := spritz.Encrypt(key, nonce, msg); !bytes.Equal(ciphertext, expected) {
+		t.Errorf("Output was \n%x\n but expected\n%x", ciphertext, expected)
+	}
+
+	decrypt := spritz.NewTransformer(key, nonce)
+	plaintext := append(decrypt(ciphertext[:10]), decrypt(ciphertext[10:])...)
+	if !bytes.Equal(plaintext, msg) {
+		t.Errorf("Decrypted %q but expected %q", plaintext, msg)
+	}
+}
+
+func TestEncryptBlock(t *testing.T) {
+	key, nonce := []byte("arcfour"), []byte("nonce")
+	record := []byte("a fixed-size record")
+
+	ciphertexts := make(map[string]bool)
+	for i := uint64(0); i < 100; i++ {
+		ciphertext := spritz.EncryptBlock(key, nonce, i, record)
+		if len(ciphertext) != len(record) {
+			t.Fatalf("Record %d was %d bytes but expected %d", i, len(ciphertext), len(record))
+		}
+
+		if ciphertexts[string(ciphertext)] {
+			t.Fatalf("Record %d reused a keystream", i)
+		}
+		ciphertexts[string(ciphertext)] = true
+
+		if plaintext := spritz.DecryptBlock(key, nonce, i, ciphertext); !bytes.Equal(plaintext, record) {
+			t.Errorf("Record %d decrypted as %q but expected %q", i, plaintext, record)
+		}
+	}
+
+	// computed from the paper's pseudocode, with the label absorbed between stops
+	expected := []byte{
+		0x24, 0x8e, 0x60, 0xa1, 0x69, 0x2b, 0x83, 0x8d,
+		0x8e, 0x1c, 0x1f, 0xde, 0xdb, 0x43, 0xf0, 0x56,
+	}
+	if keystream := spritz.EncryptBlock(key, nonce, 7, make([]byte, 16)); !bytes.Equal(keystream, expected) {
+		t.Errorf("Output was \n%x\n but expected\n%x", keystream, expected)
+	}
+
+	iv := append([]byte("nonce"), 0, 0, 0, 0, 0, 0, 0, 7)
+	if other := spritz.Encrypt(key, iv, record); bytes.Equal(other, spritz.EncryptBlock(key, nonce, 7, record)) {
+		t.Errorf("Record shared the keystream of Encrypt: %x", other)
+	}
+}
+
+func TestNonceForCounter(t *testing.T) {
+	base := []byte{
+		0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07,
+		0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+	}
+
+	nonce := spritz.NonceForCounter(base, 0x0102)
+	expected := []byte{
+		0x02, 0x00, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07,
+		0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+	}
+	if !bytes.Equal(nonce, expected) {
+		t.Errorf("Nonce was \n%x\n but expected\n%x", nonce, expected)
+	}
+
+	if base[0] != 0x00 {
+		t.Error("NonceForCounter modified the base nonce")
+	}
+
+	seen := make(map[string]bool)
+	for i := uint64(0); i < 1000; i++ {
+		n := string(spritz.NonceForCounter(base, i))
+		if seen[n] {
+			t.Fatalf("Counter %d repeated a nonce", i)
+		}
+		seen[n] = true
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Derived a nonce from a short base")
+		}
+	}()
+	spritz.NonceForCounter(base[:7], 1)
+}
+
+func TestStreamNonceCounter(t *testing.T) {
+	key, nonce := []byte("arcfour"), []byte("twelve bytes")
+	const block = spritz.CounterBlockSize
+
+	out := make([]byte, 3*block+100)
+	spritz.NewStreamNonceCounter(key, nonce, 1<<32-2).XORKeyStream(out, out)
+
+	for i, counter := range []uint32{1<<32 - 2, 1<<32 - 1, 0, 1} {
+		end := (i + 1) * block
+		if end > len(out) {
+			end = len(out)
+		}
+		expected := make([]byte, end-i*block)
+		spritz.NewStreamNonceCounter(key, nonce, counter).XORKeyStream(expected, expected)
+
+		if actual := out[i*block : end]; !bytes.Equal(actual, expected) {
+			t.Errorf("Block %d was \n%x\n but expected\n%x", i, actual[:16], expected[:16])
+		}
+
+		// the label keeps blocks apart from NewStreamIV with the same IV
+		iv := make([]byte, 16)
+		copy(iv, nonce)
+		binary.BigEndian.PutUint32(iv[12:], counter)
+		other := make([]byte, 16)
+		spritz.NewStreamIV(key, iv).XORKeyStream(other, other)
+		if bytes.Equal(other, out[i*block:i*block+16]) {
+			t.Errorf("Block %d shared the keystream of NewStreamIV: %x", i, other)
+		}
+	}
+
+	// computed from the paper's pseudocode, with the label absorbed between stops
+	known := []byte{
+		0x73, 0xdd, 0x80, 0x2a, 0xab, 0x39, 0x4d, 0x1f,
+		0x1b, 0x69, 0xee, 0x2e, 0x6c, 0xfe, 0xa5, 0x58,
+	}
+	if actual := out[3*block : 3*block+16]; !bytes.Equal(actual, known) {
+		t.Errorf("Block 1 was \n%x\n but expected\n%x", actual, known)
+	}
+
+	later := make([]byte, block+100)
+	spritz.NewStreamNonceCounter(key, nonce, 0).XORKeyStream(later, later)
+	if !bytes.Equal(later, out[2*block:]) {
+		t.Error("A later initial counter did not start at the corresponding block")
+	}
+
+	s := spritz.NewStreamNonceCounter(key, nonce, 1<<32-2)
+	for _, offset := range []int64{2*block + 50, 10, block, 3 * block} {
+		if _, err := s.Seek(offset, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+
+		actual := make([]byte, 100)
+		s.XORKeyStream(actual, actual)
+		if expected := out[offset : offset+100]; !bytes.Equal(actual, expected) {
+			t.Errorf("Output at %d was \n%x\n but expected\n%x", offset, actual, expected)
+		}
+	}
+
+	state, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := spritz.NewStream([]byte("other"))
+	if err := r.UnmarshalBinary(state); err != nil {
+		t.Fatal(err)
+	}
+	r.Reset()
+	actual := make([]byte, len(out))
+	r.XORKeyStream(actual, actual)
+	if !bytes.Equal(actual, out) {
+		t.Error("Output after unmarshaling and Reset did not match")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Created a stream with a 16-byte nonce")
+		}
+	}()
+	spritz.NewStreamNonceCounter(key, make([]byte, 16), 0)
+}
+
+func TestStreamRatchet(t *testing.T) {
+	key := []byte("arcfour")
+
+	a := make([]byte, 100)
+	spritz.NewStreamRatchet(key, 16).XORKeyStream(a, a)
+
+	s := spritz.NewStreamRatchet(key, 16)
+	b := make([]byte, 100)
+	for i := 0; i < len(b); i += 7 {
+		end := i + 7
+		if end > len(b) {
+			end = len(b)
+		}
+		s.XORKeyStream(b[i:end], b[i:end])
+	}
+
+	if !bytes.Equal(a, b) {
+		t.Errorf("Output in parts was \n%x\n but expected\n%x", b, a)
+	}
+
+	plain := spritz.Keystream(key, 100)
+	if !bytes.Equal(a[:16], plain[:16]) {
+		t.Errorf("Output before ratcheting was \n%x\n but expected\n%x", a[:16], plain[:16])
+	}
+	if bytes.Equal(a[16:32], plain[16:32]) {
+		t.Errorf("Output after ratcheting didn't change: %x", a[16:32])
+	}
+
+	s = spritz.NewStreamRatchet(key, 16)
+	if _, err := s.Seek(40, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	c := make([]byte, 60)
+	s.FillKeystream(c)
+	if !bytes.Equal(c, a[40:]) {
+		t.Errorf("Output after seeking was \n%x\n but expected\n%x", c, a[40:])
+	}
+}
+
+func TestStreamRatchetCannotRewind(t *testing.T) {
+	s := spritz.NewStreamRatchet([]byte("arcfour"), 16)
+	if _, err := s.Seek(20, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Seek(0, io.SeekStart); err == nil {
+		t.Error("Seeked backward in a ratcheting stream")
+	}
+
+	state, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(state, []byte("arcfour")) {
+		t.Error("Ratcheting stream retained its key")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Reset a ratcheting stream")
+		}
+	}()
+	s.Reset()
+}
+
 func BenchmarkStream(b *testing.B) {
 	v := []byte{'a', 'r', 'c', 'f', 'o', 'u', 'r'}
 	s := spritz.NewStream(v)
@@ -43,3 +1034,53 @@ func BenchmarkStream(b *testing.B) {
 		s.XORKeyStream(out, out)
 	}
 }
+
+func BenchmarkStreamReset(b *testing.B) {
+	s := spritz.NewStream([]byte{'a', 'r', 'c', 'f', 'o', 'u', 'r'})
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		s.Reset()
+	}
+}
+
+func BenchmarkStream1MiB(b *testing.B) {
+	s := spritz.NewStream([]byte{'a', 'r', 'c', 'f', 'o', 'u', 'r'})
+	out := make([]byte, 1<<20)
+	b.SetBytes(int64(len(out)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		s.XORKeyStream(out, out)
+	}
+}
+
+// BenchmarkStream1MiBByteAtATime measures the byte-at-a-time loop which
+// XORKeyStream replaced, for comparison with BenchmarkStream1MiB.
+func BenchmarkStream1MiBByteAtATime(b *testing.B) {
+	s := spritz.NewSponge(256)
+	s.Absorb([]byte{'a', 'r', 'c', 'f', 'o', 'u', 'r'})
+	out := make([]byte, 1<<20)
+	b.SetBytes(int64(len(out)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for j, v := range out {
+			out[j] = v ^ byte(s.Drip())
+		}
+	}
+}
+
+func BenchmarkKeystream1MiB(b *testing.B) {
+	key := []byte{'a', 'r', 'c', 'f', 'o', 'u', 'r'}
+	b.SetBytes(1 << 20)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		spritz.Keystream(key, 1<<20)
+	}
+}
@@ -0,0 +1,152 @@
+package spritz
+
+import (
+	"encoding/binary"
+	"hash"
+	"runtime"
+	"sync"
+)
+
+const treeLeafDigestSize = 32
+
+var (
+	treeLeafPersonal = []byte("spritz tree leaf")
+	treeRootPersonal = []byte("spritz tree root")
+)
+
+// NewTreeHash returns a new instance of the Spritz tree hash with the given
+// output size, which splits its input into leaves of leafSize bytes and hashes
+// them independently, in parallel, before combining them. It is meant for very
+// large inputs, where a single sponge would be the bottleneck. leafSize must be
+// positive, and the digest depends on it.
+//
+// The input is split into consecutive leaves of leafSize bytes, the last of
+// which holds the remaining 1 to leafSize bytes; an empty input is a single
+// empty leaf. The digest of leaf i, counting from zero, is the 32-byte digest
+// of NewHashPersonalized with the personalization "spritz tree leaf" after
+// writing i as an 8-byte big-endian integer followed by the leaf's contents.
+// The final digest is the size-byte digest of NewHashPersonalized with the
+// personalization "spritz tree root" after writing leafSize as an 8-byte
+// big-endian integer, the leaf digests in order, and the number of leaves as an
+// 8-byte big-endian integer. It is therefore the same however many goroutines
+// hash the leaves.
+func NewTreeHash(size, leafSize int) hash.Hash {
+	if leafSize <= 0 {
+		panic("spritz: leaf size must be positive")
+	}
+	return &treeHash{
+		size:    size,
+		leaf:    leafSize,
+		pending: make([]byte, 0, leafSize),
+	}
+}
+
+type treeHash struct {
+	size, leaf int
+	pending    []byte                     // the current, incomplete leaf
+	leaves     [][treeLeafDigestSize]byte // digests of the complete leaves
+}
+
+func (t *treeHash) Write(p []byte) (int, error) {
+	n := len(p)
+
+	if len(t.pending) > 0 {
+		m := copy(t.pending[len(t.pending):t.leaf], p)
+		t.pending = t.pending[:len(t.pending)+m]
+		p = p[m:]
+
+		if len(t.pending) < t.leaf {
+			return n, nil
+		}
+		t.leaves = append(t.leaves, leafDigest(len(t.leaves), t.pending))
+		t.pending = t.pending[:0]
+	}
+
+	if full := len(p) / t.leaf * t.leaf; full > 0 {
+		t.hashLeaves(p[:full])
+		p = p[full:]
+	}
+	t.pending = append(t.pending, p...)
+	return n, nil
+}
+
+// hashLeaves appends the digests of the complete leaves in p, which is a
+// multiple of the leaf size, spreading them across up to GOMAXPROCS goroutines.
+func (t *treeHash) hashLeaves(p []byte) {
+	first, count := len(t.leaves), len(p)/t.leaf
+	t.leaves = append(t.leaves, make([][treeLeafDigestSize]byte, count)...)
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > count {
+		workers = count
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < count; i += workers {
+				t.leaves[first+i] = leafDigest(first+i, p[i*t.leaf:(i+1)*t.leaf])
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+func (t *treeHash) Sum(b []byte) []byte {
+	leaves := t.leaves
+	if len(t.pending) > 0 || len(leaves) == 0 {
+		leaves = append(leaves[:len(leaves):len(leaves)], leafDigest(len(leaves), t.pending))
+	}
+
+	var s state
+	s.initialize(256)
+	s.absorbPersonal(treeRootPersonal)
+	absorbUint64(&s, uint64(t.leaf))
+	for i := range leaves {
+		s.absorb(leaves[i][:])
+	}
+	absorbUint64(&s, uint64(len(leaves)))
+	s.absorbStop()
+	s.absorbByte(t.size)
+
+	ret, out := sliceForAppend(b, t.size)
+	s.squeeze(out)
+	return ret
+}
+
+func (t *treeHash) Reset() {
+	t.pending = t.pending[:0]
+	t.leaves = t.leaves[:0]
+}
+
+func (t *treeHash) Size() int {
+	return t.size
+}
+
+func (t *treeHash) BlockSize() int {
+	return t.leaf
+}
+
+// leafDigest returns the digest of the leaf with the given index.
+func leafDigest(index int, leaf []byte) [treeLeafDigestSize]byte {
+	var s state
+	s.initialize(256)
+	s.absorbPersonal(treeLeafPersonal)
+	absorbUint64(&s, uint64(index))
+	s.absorb(leaf)
+	s.absorbStop()
+	s.absorbByte(treeLeafDigestSize)
+
+	var out [treeLeafDigestSize]byte
+	s.squeeze(out[:])
+	return out
+}
+
+// absorbUint64 absorbs v as an 8-byte big-endian integer.
+func absorbUint64(s *state, v uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	s.absorb(b[:])
+}
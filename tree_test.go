@@ -0,0 +1,91 @@
+package spritz_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/codahale/spritz"
+)
+
+// treeHash is a sequential reference implementation of the documented tree
+// structure.
+func treeHash(data []byte, size, leafSize int) []byte {
+	var leaves [][]byte
+	for len(data) > leafSize {
+		leaves, data = append(leaves, data[:leafSize]), data[leafSize:]
+	}
+	leaves = append(leaves, data)
+
+	var n [8]byte
+	root := spritz.NewHashPersonalized(size, []byte("spritz tree root"))
+	binary.BigEndian.PutUint64(n[:], uint64(leafSize))
+	_, _ = root.Write(n[:])
+
+	for i, leaf := range leaves {
+		h := spritz.NewHashPersonalized(32, []byte("spritz tree leaf"))
+		binary.BigEndian.PutUint64(n[:], uint64(i))
+		_, _ = h.Write(n[:])
+		_, _ = h.Write(leaf)
+		_, _ = root.Write(h.Sum(nil))
+	}
+
+	binary.BigEndian.PutUint64(n[:], uint64(len(leaves)))
+	_, _ = root.Write(n[:])
+	return root.Sum(nil)
+}
+
+func TestTreeHash(t *testing.T) {
+	data := make([]byte, 10000)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	for _, n := range []int{0, 1, 63, 64, 65, 1000, 10000} {
+		expected := treeHash(data[:n], 32, 64)
+
+		h := spritz.NewTreeHash(32, 64)
+		_, _ = h.Write(data[:n])
+		if out := h.Sum(nil); !bytes.Equal(out, expected) {
+			t.Errorf("Output for %d bytes was \n%x\n but expected\n%x", n, out, expected)
+		}
+
+		h.Reset()
+		for p := data[:n]; len(p) > 0; {
+			m := len(p)
+			if m > 10 {
+				m = 10
+			}
+			_, _ = h.Write(p[:m])
+			p = p[m:]
+		}
+		if out := h.Sum(nil); !bytes.Equal(out, expected) {
+			t.Errorf("Output for %d bytes in small writes was \n%x\n but expected\n%x", n, out, expected)
+		}
+	}
+}
+
+func TestTreeHashLeafSize(t *testing.T) {
+	a := spritz.NewTreeHash(32, 64)
+	b := spritz.NewTreeHash(32, 128)
+	_, _ = a.Write([]byte("arcfour"))
+	_, _ = b.Write([]byte("arcfour"))
+
+	if bytes.Equal(a.Sum(nil), b.Sum(nil)) {
+		t.Error("Different leaf sizes produced the same digest")
+	}
+}
+
+func BenchmarkTreeHash(b *testing.B) {
+	in := make([]byte, 1<<20)
+	h := spritz.NewTreeHash(32, 64*1024)
+	b.SetBytes(int64(len(in)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		h.Reset()
+		_, _ = h.Write(in)
+		h.Sum(nil)
+	}
+}
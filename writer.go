@@ -0,0 +1,54 @@
+package spritz
+
+import "io"
+
+// NewWriter returns an io.WriteCloser which encrypts everything written to it
+// with the Spritz cipher using the given key and writes the ciphertext to w.
+// Closing the writer zeroes its internal buffer and, if w is an io.Closer,
+// closes w.
+func NewWriter(w io.Writer, key []byte) io.WriteCloser {
+	return &writer{
+		w:   w,
+		s:   NewStream(key),
+		buf: make([]byte, 4096),
+	}
+}
+
+type writer struct {
+	w   io.Writer
+	s   *Stream
+	buf []byte
+}
+
+func (w *writer) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > len(w.buf) {
+			chunk = chunk[:len(w.buf)]
+		}
+		p = p[len(chunk):]
+
+		out := w.buf[:len(chunk)]
+		w.s.XORKeyStream(out, chunk)
+
+		m, err := w.w.Write(out)
+		n += m
+		if err != nil {
+			return n, err
+		}
+		if m != len(out) {
+			return n, io.ErrShortWrite
+		}
+	}
+	return n, nil
+}
+
+func (w *writer) Close() error {
+	for i := range w.buf {
+		w.buf[i] = 0
+	}
+	if c, ok := w.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package spritz_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/codahale/spritz"
+)
+
+func TestWriter(t *testing.T) {
+	key := []byte("arcfour")
+	msg := bytes.Repeat([]byte("hello world "), 1000)
+
+	var buf bytes.Buffer
+	w := spritz.NewWriter(&buf, key)
+	for i := 0; i < len(msg); i += 1000 {
+		if _, err := w.Write(msg[i : i+1000]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := make([]byte, len(msg))
+	spritz.NewStream(key).XORKeyStream(expected, msg)
+
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Errorf("Output for %q was \n%x\n but expected\n%x", key, buf.Bytes(), expected)
+	}
+}
+
+type shortWriter struct{}
+
+func (shortWriter) Write(p []byte) (int, error) {
+	return len(p) / 2, nil
+}
+
+type errWriter struct{}
+
+var errWrite = errors.New("write failed")
+
+func (errWriter) Write(p []byte) (int, error) {
+	return 0, errWrite
+}
+
+func TestWriterErrors(t *testing.T) {
+	w := spritz.NewWriter(shortWriter{}, []byte("arcfour"))
+	if n, err := w.Write(make([]byte, 10)); n != 5 || err != io.ErrShortWrite {
+		t.Errorf("Short write returned %d, %v", n, err)
+	}
+
+	w = spritz.NewWriter(errWriter{}, []byte("arcfour"))
+	if n, err := w.Write(make([]byte, 10)); n != 0 || err != errWrite {
+		t.Errorf("Failed write returned %d, %v", n, err)
+	}
+}